@@ -0,0 +1,122 @@
+// Package app wires the balance service: the projections built from the
+// wallet's events and the HTTP routes reading them. cmd/balancecore runs it
+// against the configured database and broker, and tests run it in process.
+package app
+
+import (
+	"balance/internal/database"
+	"balance/internal/event"
+	"balance/internal/event/handler"
+	"balance/internal/gateway"
+	"balance/internal/usecase/get_account_balance"
+	"balance/internal/usecase/get_balance_history"
+	"balance/internal/usecase/list_account_transactions"
+	"balance/internal/usecase/record_transaction"
+	"balance/internal/usecase/update_account_balance"
+	"balance/internal/web"
+	"balance/internal/web/webserver"
+	"balance/pkg/dialect"
+	"balance/pkg/messaging"
+	"balance/pkg/uow"
+	"context"
+	"database/sql"
+	"expvar"
+	"log"
+	"net/http"
+)
+
+// The sample accounts Prepare seeds, matching the ones the wallet service
+// seeds.
+const (
+	SampleAccountA = database.SampleAccountA
+	SampleAccountB = database.SampleAccountB
+)
+
+// Prepare brings the schema of db up to date and, with seed, inserts the
+// sample accounts, leaving existing rows alone.
+func Prepare(ctx context.Context, db *sql.DB, d dialect.Dialect, seed bool) error {
+	migrator, err := database.NewMigrator(db, d)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	if err != nil || !seed {
+		return err
+	}
+	return database.Seed(db, d)
+}
+
+// Topics holds the topics the projections are built from.
+var Topics = []string{"balances", "transactions"}
+
+// Service is the balance service: the handler of the messages consumed from
+// Topics and the web server reading the projections they build.
+type Service struct {
+	HandleMessage messaging.Handler
+	WebServer     *webserver.WebServer
+}
+
+// New wires the balance service on db. Messages that keep failing are parked
+// on dead-letter topics through publisher; the web server listens on port.
+func New(ctx context.Context, db *sql.DB, d dialect.Dialect, publisher messaging.Publisher, port string) *Service {
+	// Create balance database gateways
+	balanceDb := database.NewBalanceDB(db, d)
+	balanceHistoryDb := database.NewBalanceHistoryDB(db, d)
+	accountTransactionDb := database.NewAccountTransactionDB(db, d)
+
+	// Projection updates and their inbox entries commit together
+	projectionUow := uow.NewUow(ctx, db)
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.BalanceGateway {
+		return database.NewBalanceDB(tx, d)
+	})
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.BalanceHistoryGateway {
+		return database.NewBalanceHistoryDB(tx, d)
+	})
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.AccountTransactionGateway {
+		return database.NewAccountTransactionDB(tx, d)
+	})
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.InboxGateway {
+		return database.NewInboxDB(tx, d)
+	})
+
+	// Create use cases
+	getAccountBalanceUseCase := get_account_balance.NewGetAccountBalanceUseCase(balanceDb, balanceHistoryDb)
+	getBalanceHistoryUseCase := get_balance_history.NewGetBalanceHistoryUseCase(balanceHistoryDb)
+	updateAccountBalanceUseCase := update_account_balance.NewUpdateAccountBalanceUseCase(projectionUow)
+	recordTransactionUseCase := record_transaction.NewRecordTransactionUseCase(projectionUow)
+	listAccountTransactionsUseCase := list_account_transactions.NewListAccountTransactionsUseCase(accountTransactionDb)
+
+	// Route events to their handlers; failures are retried and then
+	// dead-lettered, while messages that do not match their schema are
+	// quarantined
+	router := handler.NewRouter()
+	router.Register("BalanceUpdated", handler.NewBalanceUpdatedKafkaHandler(updateAccountBalanceUseCase))
+	router.Register("TransactionCreated", handler.NewTransactionCreatedKafkaHandler(recordTransactionUseCase))
+
+	deadLetterQueue := messaging.NewDeadLetterQueue(publisher)
+	handleMessage := deadLetterQueue.WithRetry(messaging.NewRetryPolicy(), func(ctx context.Context, msg *messaging.Message) error {
+		decoded, err := event.Decode(msg.Headers, msg.Value)
+		if err != nil {
+			return messaging.Invalid(err)
+		}
+		return router.Process(handler.WithEventID(ctx, handler.DeliveredEventID(msg, decoded)), decoded)
+	})
+
+	// Setup web server
+	webserver := webserver.NewWebServer(port)
+	balanceHandler := web.NewBalanceHandler(getAccountBalanceUseCase, getBalanceHistoryUseCase)
+	webserver.AddGetHandler("/balances/{account_id}", balanceHandler.GetAccountBalance)
+	webserver.AddGetHandler("/balances/{account_id}/history", balanceHandler.GetBalanceHistory)
+	transactionHandler := web.NewTransactionHandler(listAccountTransactionsUseCase)
+	webserver.AddGetHandler("/accounts/{id}/transactions", transactionHandler.ListAccountTransactions)
+	webserver.AddGetHandler("/debug/vars", expvar.Handler().ServeHTTP)
+	webserver.AddGetHandler("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	return &Service{HandleMessage: handleMessage, WebServer: webserver}
+}
@@ -0,0 +1,54 @@
+package app_test
+
+import (
+	"balance/app"
+	"balance/internal/event"
+	"balance/pkg/dialect"
+	"balance/pkg/events"
+	"balance/pkg/messaging"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMessage(t *testing.T) {
+	t.Run("processes an event published twice once", func(t *testing.T) {
+		ctx := context.Background()
+		db, d, err := dialect.Open("sqlite://" + filepath.Join(t.TempDir(), "balance.db"))
+		require.Nil(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.Nil(t, app.Prepare(ctx, db, d, true))
+		service := app.New(ctx, db, d, messaging.NewMemoryBroker(1), "")
+
+		transactionCreated := event.NewTransactionCreated(map[string]interface{}{
+			"id":              "tx1",
+			"account_id_from": app.SampleAccountA,
+			"account_id_to":   app.SampleAccountB,
+			"amount":          10,
+			"created_at":      "2025-03-03T10:00:00Z",
+		})
+		value, headers, err := events.NewCloudEventsEncoder("/wallet-service", false, events.JSONCodec{}).Encode(transactionCreated)
+		require.Nil(t, err)
+
+		// A producer retry publishes the same event again on the next offset
+		for offset := int64(0); offset < 2; offset++ {
+			msg := &messaging.Message{Topic: "transactions", Offset: offset, Value: value, Headers: headers}
+			require.Nil(t, service.HandleMessage(ctx, msg))
+		}
+
+		var ids []string
+		rows, err := db.Query("SELECT event_id FROM processed_events")
+		require.Nil(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			require.Nil(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.Nil(t, rows.Err())
+		assert.Equal(t, []string{transactionCreated.GetID()}, ids)
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"balance/pkg/kafka"
+	"balance/pkg/messaging"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+	_ "modernc.org/sqlite"
+)
+
+// eventLogPartitions must match the wallet service when sharing an event log.
+const eventLogPartitions = 3
+
+// eventLogPath returns the SQLite event log shared with the wallet service
+// that replaces Kafka, or "" for Kafka: the one in the standalone directory,
+// or with MESSAGE_BROKER=sqlite the one at EVENT_LOG_PATH.
+func eventLogPath(standalone string) string {
+	if standalone != "" {
+		return filepath.Join(standalone, "events.db")
+	}
+	if os.Getenv("MESSAGE_BROKER") != "sqlite" {
+		return ""
+	}
+	if path := os.Getenv("EVENT_LOG_PATH"); path != "" {
+		return path
+	}
+	return "events.db"
+}
+
+// kafkaBootstrapServers returns the Kafka brokers to connect to, from
+// KAFKA_BOOTSTRAP_SERVERS.
+func kafkaBootstrapServers() string {
+	if servers := os.Getenv("KAFKA_BOOTSTRAP_SERVERS"); servers != "" {
+		return servers
+	}
+	return "kafka:29092"
+}
+
+// openEventLog opens the SQLite event log at path.
+func openEventLog(path string) (*messaging.SQLiteBroker, func(), error) {
+	db, err := sql.Open("sqlite", messaging.SQLiteDSN(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	broker, err := messaging.NewSQLiteBroker(db, eventLogPartitions)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	log.Printf("Using the SQLite event log at %s", path)
+	return broker, func() { db.Close() }, nil
+}
+
+// openPublisher connects to the event log at path, or to Kafka when path is
+// empty. The returned function flushes and releases it.
+func openPublisher(path string) (messaging.Publisher, func(), error) {
+	if path != "" {
+		return openEventLog(path)
+	}
+	producer, err := kafka.NewKafkaProducer(&ckafka.ConfigMap{"bootstrap.servers": kafkaBootstrapServers()})
+	if err != nil {
+		return nil, nil, err
+	}
+	return kafka.NewPublisher(producer), producer.Close, nil
+}
+
+// openBroker connects to the event log at path, or to Kafka when path is
+// empty. The returned function releases the broker.
+func openBroker(path string, group string, topics []string) (messaging.Publisher, messaging.Subscriber, func(), error) {
+	if path != "" {
+		broker, closeBroker, err := openEventLog(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return broker, broker.Subscriber(group, topics...), closeBroker, nil
+	}
+
+	publisher, closePublisher, err := openPublisher(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	consumer := kafka.NewConsumer(&ckafka.ConfigMap{
+		"bootstrap.servers": kafkaBootstrapServers(),
+		"group.id":          group,
+		"auto.offset.reset": "earliest",
+	}, topics)
+	consumer.OnAssigned = func(partitions []ckafka.TopicPartition) {
+		log.Printf("Assigned partitions: %v", partitions)
+	}
+	consumer.OnRevoked = func(partitions []ckafka.TopicPartition) {
+		log.Printf("Revoked partitions: %v", partitions)
+	}
+	return publisher, consumer, closePublisher, nil
+}
+
+// openReplayer returns a replayer on the event log at path, or on Kafka when
+// path is empty, identified by group. The returned function releases it.
+func openReplayer(path string, group string) (messaging.Replayer, func(), error) {
+	if path != "" {
+		return openEventLog(path)
+	}
+	return kafka.NewReplayer(&ckafka.ConfigMap{
+		"bootstrap.servers":  kafkaBootstrapServers(),
+		"group.id":           group,
+		"enable.auto.commit": false,
+	}), func() {}, nil
+}
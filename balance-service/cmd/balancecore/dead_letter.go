@@ -0,0 +1,90 @@
+package main
+
+import (
+	"balance/pkg/messaging"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runDeadLetter inspects and re-drives messages parked on a dead-letter topic,
+// or with -quarantine on the quarantine topic, of the event log at eventLog,
+// or of Kafka when it is empty:
+//
+//	balancecore dlq list [-topic balances] [-quarantine] [-from 0]
+//	balancecore dlq redrive [-topic balances] [-quarantine] [-from 0] [-partition 0 -offset 12]
+func runDeadLetter(ctx context.Context, eventLog string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: dlq list|redrive [flags]")
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("dlq "+command, flag.ContinueOnError)
+	topic := flags.String("topic", "balances", "source topic whose dead-letter topic is read")
+	quarantine := flags.Bool("quarantine", false, "read the quarantine topic holding invalid messages instead")
+	from := flags.Int64("from", 0, "offset to start reading the dead-letter topic from")
+	partition := flags.Int("partition", -1, "only re-drive the message on this dead-letter partition")
+	offset := flags.Int64("offset", -1, "only re-drive the message at this dead-letter offset")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	parkedTopic := messaging.DeadLetterTopic(*topic)
+	if *quarantine {
+		parkedTopic = messaging.QuarantineTopic(*topic)
+	}
+	replayer, closeReplayer, err := openReplayer(eventLog, fmt.Sprintf("balance-service-dlq-%d", time.Now().Unix()))
+	if err != nil {
+		return err
+	}
+	defer closeReplayer()
+	position := messaging.ReplayPosition{Offset: *from}
+
+	switch command {
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DLQ POSITION\tORIGINAL\tATTEMPTS\tFAILED AT\tERROR")
+		_, err := replayer.Replay(ctx, []string{parkedTopic}, position, func(ctx context.Context, parked *messaging.Message) error {
+			fmt.Fprintf(w, "%d@%d\t%s[%s]@%s\t%s\t%s\t%s\n",
+				parked.Partition,
+				parked.Offset,
+				parked.Headers[messaging.HeaderOriginalTopic],
+				parked.Headers[messaging.HeaderOriginalPartition],
+				parked.Headers[messaging.HeaderOriginalOffset],
+				parked.Headers[messaging.HeaderAttempts],
+				parked.Headers[messaging.HeaderFailedAt],
+				parked.Headers[messaging.HeaderError],
+			)
+			return nil
+		})
+		w.Flush()
+		return err
+	case "redrive":
+		publisher, closePublisher, err := openPublisher(eventLog)
+		if err != nil {
+			return err
+		}
+		defer closePublisher()
+		deadLetterQueue := messaging.NewDeadLetterQueue(publisher)
+		redriven := 0
+		_, err = replayer.Replay(ctx, []string{parkedTopic}, position, func(ctx context.Context, parked *messaging.Message) error {
+			if *partition >= 0 && int(parked.Partition) != *partition {
+				return nil
+			}
+			if *offset >= 0 && parked.Offset != *offset {
+				return nil
+			}
+			if err := deadLetterQueue.Redrive(ctx, parked); err != nil {
+				return err
+			}
+			redriven++
+			return nil
+		})
+		fmt.Printf("Re-drove %d message(s) onto %s\n", redriven, *topic)
+		return err
+	default:
+		return fmt.Errorf("unknown dlq command %q", command)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"balance/pkg/messaging"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.db")
+	broker, closeBroker, err := openEventLog(path)
+	require.Nil(t, err)
+	defer closeBroker()
+	deadLetterQueue := messaging.NewDeadLetterQueue(broker)
+	for _, value := range []string{"a", "b"} {
+		require.Nil(t, broker.Publish(ctx, &messaging.Message{Topic: "balances", Key: []byte("account1"), Value: []byte(value)}))
+	}
+	published, err := broker.Messages(ctx, "balances")
+	require.Nil(t, err)
+	for _, msg := range published {
+		require.Nil(t, deadLetterQueue.Park(ctx, msg, errors.New("database unavailable"), 3))
+	}
+
+	t.Run("lists the parked messages of the event log", func(t *testing.T) {
+		assert.Nil(t, runDeadLetter(ctx, path, []string{"list", "-topic", "balances"}))
+	})
+
+	t.Run("re-drives one parked message of the event log onto its topic", func(t *testing.T) {
+		parked, err := broker.Messages(ctx, messaging.DeadLetterTopic("balances"))
+		require.Nil(t, err)
+
+		err = runDeadLetter(ctx, path, []string{"redrive", "-topic", "balances", "-offset", "1"})
+
+		require.Nil(t, err)
+		messages, err := broker.Messages(ctx, "balances")
+		require.Nil(t, err)
+		require.Len(t, messages, 3)
+		assert.Equal(t, parked[1].Value, messages[2].Value)
+	})
+}
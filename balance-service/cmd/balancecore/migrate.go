@@ -0,0 +1,85 @@
+package main
+
+import (
+	"balance/internal/database"
+	"balance/pkg/dialect"
+	"balance/pkg/migrate"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runMigrate applies, reverts or reports the embedded schema migrations:
+//
+//	balancecore migrate up
+//	balancecore migrate down [-steps 1]
+//	balancecore migrate status
+//	balancecore migrate force <version>
+//
+// force records a version as applied without running it, to adopt a
+// database whose schema was created before migrations were tracked.
+func runMigrate(ctx context.Context, db *sql.DB, d dialect.Dialect, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down|status|force [flags]")
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	migrator, err := database.NewMigrator(db, d)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		logMigrations("Applied", applied)
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		logMigrations("Reverted", reverted)
+		return err
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, migration := range status {
+			appliedAt := "pending"
+			if migration.Applied {
+				appliedAt = migration.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, appliedAt)
+		}
+		return w.Flush()
+	case "force":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: migrate force <version>")
+		}
+		version, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version: %w", err)
+		}
+		return migrator.Force(ctx, version)
+	default:
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
+
+func logMigrations(verb string, migrations []migrate.Migration) {
+	for _, migration := range migrations {
+		log.Printf("%s migration %d_%s", verb, migration.Version, migration.Name)
+	}
+}
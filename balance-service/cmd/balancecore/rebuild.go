@@ -0,0 +1,255 @@
+package main
+
+import (
+	"balance/internal/database"
+	"balance/internal/event"
+	"balance/internal/event/handler"
+	"balance/internal/gateway"
+	"balance/internal/usecase/record_transaction"
+	"balance/internal/usecase/update_account_balance"
+	"balance/pkg/dialect"
+	"balance/pkg/events"
+	"balance/pkg/messaging"
+	"balance/pkg/uow"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// projection ties a topic to the tables built from it and the handlers that
+// build them.
+type projection struct {
+	tables   func(since time.Time) []database.ShadowTable
+	register func(db *sql.DB, d dialect.Dialect, router *handler.Router)
+}
+
+var projections = map[string]projection{
+	"balances": {
+		tables: func(since time.Time) []database.ShadowTable {
+			// Events carry absolute balances, so current rows are a safe
+			// starting point; sequences are reset so every replayed update
+			// applies. History keeps opening balances and anything older
+			// than the replay start.
+			history := database.ShadowTable{
+				Name: "account_balance_history",
+				Seed: "SELECT * FROM account_balance_history WHERE sequence = 0",
+			}
+			if !since.IsZero() {
+				history.Seed += " OR occurred_at < ?"
+				history.SeedArgs = []interface{}{since.UTC()}
+			}
+			return []database.ShadowTable{
+				{Name: "account_balances", Seed: "SELECT account_id, balance, 0 FROM account_balances"},
+				history,
+			}
+		},
+		register: func(db *sql.DB, d dialect.Dialect, router *handler.Router) {
+			rebuildUow := newRebuildUow(db, d)
+			uow.Register(rebuildUow, func(tx *sql.Tx) gateway.BalanceGateway {
+				return database.NewBalanceDBWithTable(tx, d, "account_balances"+database.ShadowSuffix)
+			})
+			uow.Register(rebuildUow, func(tx *sql.Tx) gateway.BalanceHistoryGateway {
+				return database.NewBalanceHistoryDBWithTable(tx, d, "account_balance_history"+database.ShadowSuffix)
+			})
+			updateAccountBalanceUseCase := update_account_balance.NewUpdateAccountBalanceUseCase(rebuildUow)
+			router.Register("BalanceUpdated", handler.NewBalanceUpdatedKafkaHandler(updateAccountBalanceUseCase))
+		},
+	},
+	"transactions": {
+		tables: func(since time.Time) []database.ShadowTable {
+			transactions := database.ShadowTable{Name: "account_transactions"}
+			if !since.IsZero() {
+				transactions.Seed = "SELECT * FROM account_transactions WHERE created_at < ?"
+				transactions.SeedArgs = []interface{}{since.UTC()}
+			}
+			return []database.ShadowTable{transactions}
+		},
+		register: func(db *sql.DB, d dialect.Dialect, router *handler.Router) {
+			rebuildUow := newRebuildUow(db, d)
+			uow.Register(rebuildUow, func(tx *sql.Tx) gateway.AccountTransactionGateway {
+				return database.NewAccountTransactionDBWithTable(tx, d, "account_transactions"+database.ShadowSuffix)
+			})
+			recordTransactionUseCase := record_transaction.NewRecordTransactionUseCase(rebuildUow)
+			router.Register("TransactionCreated", handler.NewTransactionCreatedKafkaHandler(recordTransactionUseCase))
+		},
+	},
+}
+
+// newRebuildUow returns a unit of work for writing shadow tables. Replayed
+// events carry no message id, so the inbox is only read through it, never
+// written.
+func newRebuildUow(db *sql.DB, d dialect.Dialect) *uow.Uow {
+	rebuildUow := uow.NewUow(context.Background(), db)
+	uow.Register(rebuildUow, func(tx *sql.Tx) gateway.InboxGateway {
+		return database.NewInboxDB(tx, d)
+	})
+	return rebuildUow
+}
+
+// rebuildOptions selects what a rebuild replays.
+type rebuildOptions struct {
+	Topics []string
+	// Since keeps the rows of events older than it and replays the rest.
+	Since time.Time
+	// Offset starts the replay at this offset on every partition instead of
+	// at Since, which it requires.
+	Offset int64
+	DryRun bool
+}
+
+// runRebuild rebuilds the projections fed by the selected topics from the
+// event log at eventLog, or from Kafka when it is empty. See rebuild.
+func runRebuild(ctx context.Context, db *sql.DB, d dialect.Dialect, eventLog string, args []string) error {
+	flags := flag.NewFlagSet("rebuild", flag.ContinueOnError)
+	topics := flags.String("topics", "balances", "comma-separated topics to replay: balances, transactions")
+	offset := flags.Int64("offset", 0, "offset to start replaying from on every partition; needs -since, the time of the oldest event it replays")
+	since := flags.String("since", "", "RFC3339 timestamp; rows of older events are kept and newer events are replayed")
+	group := flags.String("group", fmt.Sprintf("balance-service-rebuild-%d", time.Now().Unix()), "consumer group identifying the replay")
+	dryRun := flags.Bool("dry-run", false, "replay into the shadow tables and drop them instead of swapping")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	options := rebuildOptions{Topics: strings.Split(*topics, ","), Offset: *offset, DryRun: *dryRun}
+	if *since != "" {
+		at, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("invalid -since: %w", err)
+		}
+		options.Since = at
+	}
+
+	replayer, closeReplayer, err := openReplayer(eventLog, *group)
+	if err != nil {
+		return err
+	}
+	defer closeReplayer()
+	return rebuild(ctx, db, d, replayer, options)
+}
+
+// rebuild rebuilds the projections fed by the selected topics into shadow
+// tables by replaying the topics, then swaps them in. The live tables keep
+// serving reads until the swap.
+//
+// The live consumer keeps running: the swap fences the live tables, replays
+// the messages published since the replay ended into the shadows and copies
+// them over in one transaction, so nothing the live consumer wrote in the
+// meantime is lost.
+func rebuild(ctx context.Context, db *sql.DB, d dialect.Dialect, replayer messaging.Replayer, options rebuildOptions) error {
+	// Rows are kept by the time of their event, so the replay has to start
+	// no later than that time for none to be lost
+	if options.Offset != 0 && options.Since.IsZero() {
+		return errors.New("-offset needs -since, the time of the oldest event it replays, to tell which rows to keep")
+	}
+	position := messaging.ReplayPosition{Offset: options.Offset, Timestamp: options.Since}
+	if options.Offset != 0 {
+		position.Timestamp = time.Time{}
+	}
+
+	tables := []database.ShadowTable{}
+	router := handler.NewRouter()
+	for _, topic := range options.Topics {
+		p, ok := projections[topic]
+		if !ok {
+			return fmt.Errorf("no projection is built from topic %q", topic)
+		}
+		tables = append(tables, p.tables(options.Since)...)
+		p.register(db, d, router)
+	}
+
+	rebuildDb := database.NewProjectionRebuildDB(db, d)
+	if err := rebuildDb.Prepare(tables); err != nil {
+		rebuildDb.Discard(tables)
+		return err
+	}
+	defer rebuildDb.Discard(tables)
+
+	replay := newReplayHandler(router, options)
+	ends, err := replayer.Replay(ctx, options.Topics, position, replay.handle)
+	if err != nil {
+		return err
+	}
+	log.Printf("Replayed %d event(s) from %s\n", replay.replayed, strings.Join(options.Topics, ","))
+
+	if options.DryRun {
+		log.Print("Dry run: dropping shadow tables")
+		return nil
+	}
+
+	swapUow := uow.NewUow(ctx, db)
+	err = swapUow.Do(ctx, func(ctx context.Context) error {
+		tx, _ := uow.TxFromContext(ctx)
+		if err := rebuildDb.Fence(tx, tables); err != nil {
+			return err
+		}
+		// The use cases run in savepoints of this transaction
+		tail := newReplayHandler(router, rebuildOptions{Since: options.Since})
+		if _, err := replayer.Replay(ctx, options.Topics, messaging.ReplayPosition{Offsets: ends}, tail.handle); err != nil {
+			return err
+		}
+		log.Printf("Caught up with %d event(s) handled by the live consumer during the rebuild\n", tail.replayed)
+		return rebuildDb.Swap(tx, tables)
+	})
+	if err != nil {
+		return err
+	}
+	log.Print("Swapped rebuilt projections in")
+	return nil
+}
+
+// replayHandler feeds replayed messages to the rebuild's router.
+type replayHandler struct {
+	router   *handler.Router
+	options  rebuildOptions
+	started  map[messaging.TopicPartition]bool
+	replayed int
+}
+
+func newReplayHandler(router *handler.Router, options rebuildOptions) *replayHandler {
+	return &replayHandler{
+		router:  router,
+		options: options,
+		started: map[messaging.TopicPartition]bool{},
+	}
+}
+
+// handle skips events older than the rebuild's Since, whose rows were kept,
+// and refuses to start a partition past it, which would lose the events
+// before the start.
+func (h *replayHandler) handle(ctx context.Context, msg *messaging.Message) error {
+	decoded, err := event.Decode(msg.Headers, msg.Value)
+	if err != nil {
+		log.Printf("Skipping message %s: %v", messaging.MessageID(msg), err)
+		return nil
+	}
+	partition := messaging.TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+	first := !h.started[partition]
+	h.started[partition] = true
+
+	if !h.options.Since.IsZero() {
+		at := occurredAt(decoded)
+		if first && h.options.Offset != 0 && msg.Offset > 0 && at.After(h.options.Since) {
+			return fmt.Errorf("-offset %d starts %s[%d] at an event from %s, after -since; the events before it would be lost",
+				h.options.Offset, msg.Topic, msg.Partition, at.Format(time.RFC3339))
+		}
+		if !at.IsZero() && at.Before(h.options.Since) {
+			return nil
+		}
+	}
+	h.replayed++
+	return h.router.Process(ctx, decoded)
+}
+
+// occurredAt returns when an event happened according to its payload, or the
+// zero time when the payload does not tell.
+func occurredAt(decoded events.EventInterface) time.Time {
+	payload, _ := decoded.GetPayload().(map[string]interface{})
+	value, _ := payload["created_at"].(string)
+	at, _ := time.Parse(time.RFC3339Nano, value)
+	return at
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"balance/app"
+	"balance/internal/database"
+	"balance/internal/event"
+	"balance/internal/event/handler"
+	"balance/internal/gateway"
+	"balance/internal/usecase/record_transaction"
+	"balance/internal/usecase/update_account_balance"
+	"balance/pkg/dialect"
+	"balance/pkg/messaging"
+	"balance/pkg/uow"
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var firstTransfer = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// rebuildTest holds a balance database and the event log feeding it, kept up
+// to date by a live consumer the test drives.
+type rebuildTest struct {
+	t        *testing.T
+	db       *sql.DB
+	broker   *messaging.SQLiteBroker
+	live     *handler.Router
+	consumed messaging.Offsets
+	sent     int
+}
+
+func newRebuildTest(t *testing.T) *rebuildTest {
+	dir := t.TempDir()
+	db, d, err := dialect.Open("sqlite://" + filepath.Join(dir, "balance.db"))
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.Nil(t, app.Prepare(context.Background(), db, d, true))
+
+	broker, closeBroker, err := openEventLog(filepath.Join(dir, "events.db"))
+	require.Nil(t, err)
+	t.Cleanup(closeBroker)
+
+	liveUow := uow.NewUow(context.Background(), db)
+	uow.Register(liveUow, func(tx *sql.Tx) gateway.BalanceGateway { return database.NewBalanceDB(tx, d) })
+	uow.Register(liveUow, func(tx *sql.Tx) gateway.BalanceHistoryGateway { return database.NewBalanceHistoryDB(tx, d) })
+	uow.Register(liveUow, func(tx *sql.Tx) gateway.AccountTransactionGateway { return database.NewAccountTransactionDB(tx, d) })
+	uow.Register(liveUow, func(tx *sql.Tx) gateway.InboxGateway { return database.NewInboxDB(tx, d) })
+	live := handler.NewRouter()
+	live.Register("BalanceUpdated", handler.NewBalanceUpdatedKafkaHandler(update_account_balance.NewUpdateAccountBalanceUseCase(liveUow)))
+	live.Register("TransactionCreated", handler.NewTransactionCreatedKafkaHandler(record_transaction.NewRecordTransactionUseCase(liveUow)))
+
+	return &rebuildTest{t: t, db: db, broker: broker, live: live}
+}
+
+// transfer publishes the events of the next transfer of 10 from sample
+// account A to B, an hour after the previous one. Everything shares a key so
+// every topic has a single partition and offsets count the events.
+func (r *rebuildTest) transfer() {
+	r.sent++
+	n := r.sent
+	at := firstTransfer.Add(time.Duration(n-1) * time.Hour).Format(time.RFC3339)
+	values := map[string][]string{
+		"balances": {
+			fmt.Sprintf(`{"name":"BalanceUpdated","payload":{"account_id":%q,"balance":%d,"sequence":%d,"transaction_id":"tx-%d","created_at":%q}}`,
+				database.SampleAccountA, 100-10*n, n, n, at),
+			fmt.Sprintf(`{"name":"BalanceUpdated","payload":{"account_id":%q,"balance":%d,"sequence":%d,"transaction_id":"tx-%d","created_at":%q}}`,
+				database.SampleAccountB, 100+10*n, n, n, at),
+		},
+		"transactions": {
+			fmt.Sprintf(`{"name":"TransactionCreated","payload":{"id":"tx-%d","account_id_from":%q,"account_id_to":%q,"amount":10,"created_at":%q}}`,
+				n, database.SampleAccountA, database.SampleAccountB, at),
+		},
+	}
+	for topic, messages := range values {
+		for _, value := range messages {
+			msg := &messaging.Message{Topic: topic, Key: []byte(database.SampleAccountA), Value: []byte(value)}
+			require.Nil(r.t, r.broker.Publish(context.Background(), msg))
+		}
+	}
+}
+
+// consume has the live consumer handle everything published since it last ran.
+func (r *rebuildTest) consume() {
+	ends, err := r.broker.Replay(context.Background(), []string{"balances", "transactions"}, messaging.ReplayPosition{Offsets: r.consumed},
+		func(ctx context.Context, msg *messaging.Message) error {
+			decoded, err := event.Decode(msg.Headers, msg.Value)
+			if err != nil {
+				return err
+			}
+			return r.live.Process(handler.WithEventID(ctx, handler.DeliveredEventID(msg, decoded)), decoded)
+		})
+	require.Nil(r.t, err)
+	r.consumed = ends
+}
+
+func (r *rebuildTest) count(table string) int {
+	var n int
+	require.Nil(r.t, r.db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+	return n
+}
+
+func (r *rebuildTest) exists(table string) bool {
+	var n int
+	require.Nil(r.t, r.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", table).Scan(&n))
+	return n > 0
+}
+
+func (r *rebuildTest) counts() map[string]int {
+	counts := map[string]int{}
+	for _, table := range []string{"account_balances", "account_balance_history", "account_transactions"} {
+		counts[table] = r.count(table)
+	}
+	return counts
+}
+
+func (r *rebuildTest) balance(accountId string) float64 {
+	account, err := database.NewBalanceDB(r.db, dialect.SQLite).FindById(accountId)
+	require.Nil(r.t, err)
+	return account.Balance
+}
+
+func (r *rebuildTest) rebuild(replayer messaging.Replayer, options rebuildOptions) error {
+	options.Topics = []string{"balances", "transactions"}
+	return rebuild(context.Background(), r.db, dialect.SQLite, replayer, options)
+}
+
+// replayerFunc runs after once the first replay returns, standing in for the
+// live consumer handling events while a rebuild is in progress.
+type replayerFunc struct {
+	messaging.Replayer
+	after func()
+}
+
+func (r *replayerFunc) Replay(ctx context.Context, topics []string, from messaging.ReplayPosition, handle messaging.Handler) (messaging.Offsets, error) {
+	ends, err := r.Replayer.Replay(ctx, topics, from, handle)
+	if r.after != nil {
+		r.after()
+		r.after = nil
+	}
+	return ends, err
+}
+
+func TestRebuild(t *testing.T) {
+	t.Run("rebuilds the live tables from the whole log", func(t *testing.T) {
+		r := newRebuildTest(t)
+		for i := 0; i < 4; i++ {
+			r.transfer()
+		}
+		r.consume()
+		live := r.counts()
+
+		require.Nil(t, r.rebuild(r.broker, rebuildOptions{}))
+
+		assert.Equal(t, live, r.counts())
+		assert.Equal(t, 60.0, r.balance(database.SampleAccountA))
+		assert.Equal(t, 140.0, r.balance(database.SampleAccountB))
+		assert.False(t, r.exists("account_transactions_rebuild"))
+	})
+
+	t.Run("keeps the rows before -since and replays from -offset", func(t *testing.T) {
+		r := newRebuildTest(t)
+		for i := 0; i < 4; i++ {
+			r.transfer()
+		}
+		r.consume()
+		live := r.counts()
+
+		// Offset 2 is the third transfer on transactions and the second on
+		// balances, which the event time skips
+		err := r.rebuild(r.broker, rebuildOptions{Since: firstTransfer.Add(2 * time.Hour), Offset: 2})
+
+		require.Nil(t, err)
+		assert.Equal(t, live, r.counts())
+		assert.Equal(t, 60.0, r.balance(database.SampleAccountA))
+		assert.Equal(t, 140.0, r.balance(database.SampleAccountB))
+	})
+
+	t.Run("refuses an -offset past -since", func(t *testing.T) {
+		r := newRebuildTest(t)
+		for i := 0; i < 4; i++ {
+			r.transfer()
+		}
+		r.consume()
+		live := r.counts()
+
+		err := r.rebuild(r.broker, rebuildOptions{Since: firstTransfer.Add(time.Hour), Offset: 3})
+
+		assert.ErrorContains(t, err, "would be lost")
+		assert.Equal(t, live, r.counts())
+	})
+
+	t.Run("refuses an -offset without -since", func(t *testing.T) {
+		r := newRebuildTest(t)
+
+		err := r.rebuild(r.broker, rebuildOptions{Offset: 2})
+
+		assert.ErrorContains(t, err, "-offset needs -since")
+	})
+
+	t.Run("keeps what the live consumer handles during the rebuild", func(t *testing.T) {
+		r := newRebuildTest(t)
+		for i := 0; i < 2; i++ {
+			r.transfer()
+		}
+		r.consume()
+		replayer := &replayerFunc{Replayer: r.broker, after: func() {
+			r.transfer()
+			r.consume()
+		}}
+
+		require.Nil(t, r.rebuild(replayer, rebuildOptions{}))
+
+		assert.Equal(t, 6, r.count("account_transactions"))
+		assert.Equal(t, 70.0, r.balance(database.SampleAccountA))
+		assert.Equal(t, 130.0, r.balance(database.SampleAccountB))
+	})
+
+	t.Run("drops the shadow tables on a dry run", func(t *testing.T) {
+		r := newRebuildTest(t)
+		r.transfer()
+		r.consume()
+		live := r.counts()
+
+		require.Nil(t, r.rebuild(r.broker, rebuildOptions{DryRun: true}))
+
+		assert.Equal(t, live, r.counts())
+		assert.False(t, r.exists("account_balances_rebuild"))
+	})
+}
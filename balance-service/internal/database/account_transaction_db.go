@@ -0,0 +1,94 @@
+package database
+
+import (
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"fmt"
+)
+
+type AccountTransactionDB struct {
+	DB      DBTX
+	Dialect dialect.Dialect
+	Table   string
+}
+
+func NewAccountTransactionDB(db DBTX, d dialect.Dialect) *AccountTransactionDB {
+	return NewAccountTransactionDBWithTable(db, d, "account_transactions")
+}
+
+func NewAccountTransactionDBWithTable(db DBTX, d dialect.Dialect, table string) *AccountTransactionDB {
+	return &AccountTransactionDB{
+		DB:      db,
+		Dialect: d,
+		Table:   table,
+	}
+}
+
+func (a *AccountTransactionDB) Save(accountTransaction *entity.AccountTransaction) error {
+	stmt, err := a.DB.Prepare(a.Dialect.Rebind(fmt.Sprintf(`INSERT INTO %s
+		(id, transaction_id, account_id, counterparty_account_id, direction, amount, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, a.Table)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		accountTransaction.Id,
+		accountTransaction.TransactionId,
+		accountTransaction.AccountId,
+		accountTransaction.CounterpartyAccountId,
+		accountTransaction.Direction,
+		accountTransaction.Amount,
+		normalizeTime(accountTransaction.CreatedAt),
+	)
+	return err
+}
+
+func (a *AccountTransactionDB) FindByTransactionId(transactionId string) ([]*entity.AccountTransaction, error) {
+	rows, err := a.DB.Query(a.Dialect.Rebind(fmt.Sprintf(`SELECT id, transaction_id, account_id, counterparty_account_id, direction, amount, created_at
+		FROM %s
+		WHERE transaction_id = ?`, a.Table)), transactionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAccountTransactions(rows)
+}
+
+func (a *AccountTransactionDB) FindByAccountId(accountId string, limit, offset int) ([]*entity.AccountTransaction, error) {
+	rows, err := a.DB.Query(a.Dialect.Rebind(fmt.Sprintf(`SELECT id, transaction_id, account_id, counterparty_account_id, direction, amount, created_at
+		FROM %s
+		WHERE account_id = ?
+		ORDER BY created_at DESC, id
+		LIMIT ? OFFSET ?`, a.Table)), accountId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAccountTransactions(rows)
+}
+
+func scanAccountTransactions(rows *sql.Rows) ([]*entity.AccountTransaction, error) {
+	accountTransactions := []*entity.AccountTransaction{}
+	for rows.Next() {
+		var accountTransaction entity.AccountTransaction
+		err := rows.Scan(
+			&accountTransaction.Id,
+			&accountTransaction.TransactionId,
+			&accountTransaction.AccountId,
+			&accountTransaction.CounterpartyAccountId,
+			&accountTransaction.Direction,
+			&accountTransaction.Amount,
+			&accountTransaction.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		accountTransactions = append(accountTransactions, &accountTransaction)
+	}
+	return accountTransactions, rows.Err()
+}
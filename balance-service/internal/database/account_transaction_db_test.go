@@ -0,0 +1,104 @@
+package database_test
+
+import (
+	"balance/internal/database"
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+)
+
+type AccountTransactionDBTestSuite struct {
+	suite.Suite
+	DB                   *sql.DB
+	accountTransactionDB *database.AccountTransactionDB
+}
+
+func (s *AccountTransactionDBTestSuite) SetupSuite() {
+	db, err := sql.Open("sqlite", ":memory:")
+	s.Nil(err)
+	s.DB = db
+	s.accountTransactionDB = database.NewAccountTransactionDB(db, dialect.SQLite)
+	migrateTestDB(s.T(), db)
+}
+
+func (s *AccountTransactionDBTestSuite) TearDownSuite() {
+	s.DB.Close()
+}
+
+func (s *AccountTransactionDBTestSuite) TearDownTest() {
+	s.DB.Exec("DELETE FROM account_transactions")
+}
+
+func TestAccountTransactionDBTestSuite(t *testing.T) {
+	suite.Run(t, new(AccountTransactionDBTestSuite))
+}
+
+func (s *AccountTransactionDBTestSuite) saveTransfer(transactionId, from, to string, amount float64, createdAt time.Time) {
+	debit, _ := entity.NewAccountTransaction(transactionId, from, to, entity.DirectionDebit, amount, createdAt)
+	credit, _ := entity.NewAccountTransaction(transactionId, to, from, entity.DirectionCredit, amount, createdAt)
+	s.Nil(s.accountTransactionDB.Save(debit))
+	s.Nil(s.accountTransactionDB.Save(credit))
+}
+
+func (s *AccountTransactionDBTestSuite) TestSave() {
+	createdAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	accountTransaction, _ := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 10.0, createdAt)
+	err := s.accountTransactionDB.Save(accountTransaction)
+	s.Nil(err)
+
+	var counterparty, direction string
+	var amount float64
+	err = s.DB.QueryRow("SELECT counterparty_account_id, direction, amount FROM account_transactions WHERE id = ?", accountTransaction.Id).
+		Scan(&counterparty, &direction, &amount)
+	s.Nil(err)
+	s.Equal("account2", counterparty)
+	s.Equal(entity.DirectionDebit, direction)
+	s.Equal(10.0, amount)
+}
+
+func (s *AccountTransactionDBTestSuite) TestSave_MustFailForDuplicateAccountSide() {
+	createdAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	accountTransaction, _ := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 10.0, createdAt)
+	s.Nil(s.accountTransactionDB.Save(accountTransaction))
+
+	duplicate, _ := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 10.0, createdAt)
+	s.NotNil(s.accountTransactionDB.Save(duplicate))
+}
+
+func (s *AccountTransactionDBTestSuite) TestFindByTransactionId() {
+	s.saveTransfer("tx1", "account1", "account2", 10.0, time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC))
+
+	accountTransactions, err := s.accountTransactionDB.FindByTransactionId("tx1")
+	s.Nil(err)
+	s.Len(accountTransactions, 2)
+
+	accountTransactions, err = s.accountTransactionDB.FindByTransactionId("tx_not_exists")
+	s.Nil(err)
+	s.Empty(accountTransactions)
+}
+
+func (s *AccountTransactionDBTestSuite) TestFindByAccountIdReturnsNewestFirstWithPagination() {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.saveTransfer("tx1", "account1", "account2", 10.0, base)
+	s.saveTransfer("tx2", "account2", "account1", 5.0, base.Add(time.Hour))
+	s.saveTransfer("tx3", "account1", "account3", 1.0, base.Add(2*time.Hour))
+
+	accountTransactions, err := s.accountTransactionDB.FindByAccountId("account1", 2, 0)
+	s.Nil(err)
+	s.Len(accountTransactions, 2)
+	s.Equal("tx3", accountTransactions[0].TransactionId)
+	s.Equal("account3", accountTransactions[0].CounterpartyAccountId)
+	s.Equal("tx2", accountTransactions[1].TransactionId)
+	s.Equal(entity.DirectionCredit, accountTransactions[1].Direction)
+	s.True(accountTransactions[1].CreatedAt.Equal(base.Add(time.Hour)))
+
+	accountTransactions, err = s.accountTransactionDB.FindByAccountId("account1", 2, 2)
+	s.Nil(err)
+	s.Len(accountTransactions, 1)
+	s.Equal("tx1", accountTransactions[0].TransactionId)
+}
@@ -2,30 +2,39 @@ package database
 
 import (
 	"balance/internal/entity"
+	"balance/pkg/dialect"
 	"database/sql"
 	"errors"
 )
 
 type BalanceDB struct {
-	DB *sql.DB
+	DB      DBTX
+	Dialect dialect.Dialect
+	Table   string
 }
 
-func NewBalanceDB(db *sql.DB) *BalanceDB {
+func NewBalanceDB(db DBTX, d dialect.Dialect) *BalanceDB {
+	return NewBalanceDBWithTable(db, d, "account_balances")
+}
+
+func NewBalanceDBWithTable(db DBTX, d dialect.Dialect, table string) *BalanceDB {
 	return &BalanceDB{
-		DB: db,
+		DB:      db,
+		Dialect: d,
+		Table:   table,
 	}
 }
 
 func (b *BalanceDB) FindById(id string) (*entity.AccountBalance, error) {
 	var balance entity.AccountBalance
-	stmt, err := b.DB.Prepare("SELECT account_id, balance FROM account_balances WHERE account_id = ?")
+	stmt, err := b.DB.Prepare(b.Dialect.Rebind("SELECT account_id, balance, sequence FROM " + b.Table + " WHERE account_id = ?" + forUpdate(b.DB, b.Dialect)))
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
 	row := stmt.QueryRow(id)
-	err = row.Scan(&balance.AccountId, &balance.Balance)
+	err = row.Scan(&balance.AccountId, &balance.Balance, &balance.Sequence)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -37,13 +46,13 @@ func (b *BalanceDB) FindById(id string) (*entity.AccountBalance, error) {
 }
 
 func (b *BalanceDB) Save(account *entity.AccountBalance) error {
-	stmt, err := b.DB.Prepare("INSERT INTO account_balances (account_id, balance) VALUES (?, ?)")
+	stmt, err := b.DB.Prepare(b.Dialect.Rebind("INSERT INTO " + b.Table + " (account_id, balance, sequence) VALUES (?, ?, ?)"))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(account.AccountId, account.Balance)
+	_, err = stmt.Exec(account.AccountId, account.Balance, account.Sequence)
 	if err != nil {
 		return err
 	}
@@ -52,13 +61,13 @@ func (b *BalanceDB) Save(account *entity.AccountBalance) error {
 }
 
 func (b *BalanceDB) UpdateBalance(account *entity.AccountBalance) error {
-	stmt, err := b.DB.Prepare("UPDATE account_balances SET balance = ? WHERE account_id = ?")
+	stmt, err := b.DB.Prepare(b.Dialect.Rebind("UPDATE " + b.Table + " SET balance = ?, sequence = ? WHERE account_id = ?"))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.Exec(account.Balance, account.AccountId)
+	result, err := stmt.Exec(account.Balance, account.Sequence, account.AccountId)
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package database_test
 import (
 	"balance/internal/database"
 	"balance/internal/entity"
+	"balance/pkg/dialect"
 	"database/sql"
 	"testing"
 
@@ -20,17 +21,8 @@ func (s *BalanceDBTestSuite) SetupSuite() {
 	db, err := sql.Open("sqlite", ":memory:")
 	s.Nil(err)
 	s.DB = db
-	s.balanceDB = database.NewBalanceDB(db)
-	s.createTable()
-}
-
-func (s *BalanceDBTestSuite) createTable() {
-	table := `CREATE TABLE account_balances (
-        account_id TEXT PRIMARY KEY,
-        balance REAL NOT NULL
-    );`
-	_, err := s.DB.Exec(table)
-	s.Nil(err)
+	s.balanceDB = database.NewBalanceDB(db, dialect.SQLite)
+	migrateTestDB(s.T(), db)
 }
 
 func (s *BalanceDBTestSuite) TearDownSuite() {
@@ -101,6 +93,20 @@ func (s *BalanceDBTestSuite) TestUpdateBalance() {
 	s.Equal(200.0, balance)
 }
 
+func (s *BalanceDBTestSuite) TestUpdateBalancePersistsSequence() {
+	s.DB.Exec("INSERT INTO account_balances (account_id, balance) VALUES (?, ?)", "account1", 100.0)
+
+	account, _ := entity.NewBalance("account1", 200.0)
+	account.Sequence = 7
+	err := s.balanceDB.UpdateBalance(account)
+	s.Nil(err)
+
+	found, err := s.balanceDB.FindById("account1")
+	s.Nil(err)
+	s.Equal(200.0, found.Balance)
+	s.Equal(int64(7), found.Sequence)
+}
+
 func (s *BalanceDBTestSuite) TestUpdateBalanceWithNonExistingAccount() {
 	account, _ := entity.NewBalance("account_not_exists", 200.0)
 	err := s.balanceDB.UpdateBalance(account)
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type BalanceHistoryDB struct {
+	DB      DBTX
+	Dialect dialect.Dialect
+	Table   string
+}
+
+func NewBalanceHistoryDB(db DBTX, d dialect.Dialect) *BalanceHistoryDB {
+	return NewBalanceHistoryDBWithTable(db, d, "account_balance_history")
+}
+
+func NewBalanceHistoryDBWithTable(db DBTX, d dialect.Dialect, table string) *BalanceHistoryDB {
+	return &BalanceHistoryDB{
+		DB:      db,
+		Dialect: d,
+		Table:   table,
+	}
+}
+
+func (b *BalanceHistoryDB) Save(change *entity.BalanceChange) error {
+	stmt, err := b.DB.Prepare(b.Dialect.Rebind(fmt.Sprintf(`INSERT INTO %s
+		(id, account_id, balance, sequence, transaction_id, occurred_at) VALUES (?, ?, ?, ?, ?, ?)`, b.Table)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		change.Id,
+		change.AccountId,
+		change.Balance,
+		change.Sequence,
+		change.TransactionId,
+		normalizeTime(change.OccurredAt),
+	)
+	return err
+}
+
+func (b *BalanceHistoryDB) FindByAccountId(accountId string, limit, offset int) ([]*entity.BalanceChange, error) {
+	rows, err := b.DB.Query(b.Dialect.Rebind(fmt.Sprintf(`SELECT id, account_id, balance, sequence, transaction_id, occurred_at
+		FROM %s
+		WHERE account_id = ?
+		ORDER BY occurred_at DESC, sequence DESC
+		LIMIT ? OFFSET ?`, b.Table)), accountId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*entity.BalanceChange{}
+	for rows.Next() {
+		var change entity.BalanceChange
+		err := rows.Scan(
+			&change.Id,
+			&change.AccountId,
+			&change.Balance,
+			&change.Sequence,
+			&change.TransactionId,
+			&change.OccurredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
+
+func (b *BalanceHistoryDB) FindLatestAt(accountId string, at time.Time) (*entity.BalanceChange, error) {
+	var change entity.BalanceChange
+	row := b.DB.QueryRow(b.Dialect.Rebind(fmt.Sprintf(`SELECT id, account_id, balance, sequence, transaction_id, occurred_at
+		FROM %s
+		WHERE account_id = ? AND occurred_at <= ?
+		ORDER BY occurred_at DESC, sequence DESC
+		LIMIT 1`, b.Table)), accountId, normalizeTime(at))
+	err := row.Scan(
+		&change.Id,
+		&change.AccountId,
+		&change.Balance,
+		&change.Sequence,
+		&change.TransactionId,
+		&change.OccurredAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+// HasSequence reports whether the change with the given sequence of an
+// account is recorded.
+func (b *BalanceHistoryDB) HasSequence(accountId string, sequence int64) (bool, error) {
+	var count int
+	err := b.DB.QueryRow(b.Dialect.Rebind(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE account_id = ? AND sequence = ?`, b.Table)),
+		accountId, sequence).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// normalizeTime stores instants in UTC with second precision, matching the
+// DATETIME column, so comparisons behave the same on every driver.
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
@@ -0,0 +1,128 @@
+package database_test
+
+import (
+	"balance/internal/database"
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+)
+
+type BalanceHistoryDBTestSuite struct {
+	suite.Suite
+	DB               *sql.DB
+	balanceHistoryDB *database.BalanceHistoryDB
+}
+
+func (s *BalanceHistoryDBTestSuite) SetupSuite() {
+	db, err := sql.Open("sqlite", ":memory:")
+	s.Nil(err)
+	s.DB = db
+	s.balanceHistoryDB = database.NewBalanceHistoryDB(db, dialect.SQLite)
+	migrateTestDB(s.T(), db)
+}
+
+func (s *BalanceHistoryDBTestSuite) TearDownSuite() {
+	s.DB.Close()
+}
+
+func (s *BalanceHistoryDBTestSuite) TearDownTest() {
+	s.DB.Exec("DELETE FROM account_balance_history")
+}
+
+func TestBalanceHistoryDBTestSuite(t *testing.T) {
+	suite.Run(t, new(BalanceHistoryDBTestSuite))
+}
+
+func (s *BalanceHistoryDBTestSuite) saveChange(accountId string, balance float64, sequence int64, occurredAt time.Time) {
+	change, err := entity.NewBalanceChange(accountId, balance, sequence, "tx", occurredAt)
+	s.Nil(err)
+	s.Nil(s.balanceHistoryDB.Save(change))
+}
+
+func (s *BalanceHistoryDBTestSuite) TestSave() {
+	occurredAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	change, _ := entity.NewBalanceChange("account1", 90.0, 1, "tx1", occurredAt)
+	err := s.balanceHistoryDB.Save(change)
+	s.Nil(err)
+
+	var accountId, transactionId string
+	var balance float64
+	err = s.DB.QueryRow("SELECT account_id, balance, transaction_id FROM account_balance_history WHERE id = ?", change.Id).
+		Scan(&accountId, &balance, &transactionId)
+	s.Nil(err)
+	s.Equal("account1", accountId)
+	s.Equal(90.0, balance)
+	s.Equal("tx1", transactionId)
+}
+
+func (s *BalanceHistoryDBTestSuite) TestFindByAccountIdReturnsNewestFirstWithPagination() {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.saveChange("account1", 100.0, 1, base)
+	s.saveChange("account1", 90.0, 2, base.Add(24*time.Hour))
+	s.saveChange("account1", 80.0, 3, base.Add(48*time.Hour))
+	s.saveChange("account2", 10.0, 1, base)
+
+	changes, err := s.balanceHistoryDB.FindByAccountId("account1", 2, 0)
+	s.Nil(err)
+	s.Len(changes, 2)
+	s.Equal(int64(3), changes[0].Sequence)
+	s.Equal(int64(2), changes[1].Sequence)
+	s.True(changes[0].OccurredAt.Equal(base.Add(48 * time.Hour)))
+
+	changes, err = s.balanceHistoryDB.FindByAccountId("account1", 2, 2)
+	s.Nil(err)
+	s.Len(changes, 1)
+	s.Equal(int64(1), changes[0].Sequence)
+}
+
+func (s *BalanceHistoryDBTestSuite) TestFindByAccountIdReturnsEmptyWhenNoHistory() {
+	changes, err := s.balanceHistoryDB.FindByAccountId("account_not_exists", 10, 0)
+	s.Nil(err)
+	s.Empty(changes)
+}
+
+func (s *BalanceHistoryDBTestSuite) TestFindLatestAt() {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.saveChange("account1", 100.0, 1, base)
+	s.saveChange("account1", 90.0, 2, base.Add(24*time.Hour))
+	s.saveChange("account1", 80.0, 3, base.Add(48*time.Hour))
+
+	change, err := s.balanceHistoryDB.FindLatestAt("account1", base.Add(36*time.Hour))
+	s.Nil(err)
+	s.NotNil(change)
+	s.Equal(90.0, change.Balance)
+
+	change, err = s.balanceHistoryDB.FindLatestAt("account1", base.Add(48*time.Hour))
+	s.Nil(err)
+	s.Equal(80.0, change.Balance)
+}
+
+func (s *BalanceHistoryDBTestSuite) TestFindLatestAtReturnsNilBeforeFirstChange() {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.saveChange("account1", 100.0, 1, base)
+
+	change, err := s.balanceHistoryDB.FindLatestAt("account1", base.Add(-time.Hour))
+	s.Nil(err)
+	s.Nil(change)
+}
+
+func (s *BalanceHistoryDBTestSuite) TestHasSequence() {
+	s.saveChange("account1", 90.0, 2, time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC))
+
+	recorded, err := s.balanceHistoryDB.HasSequence("account1", 2)
+	s.Nil(err)
+	s.True(recorded)
+
+	recorded, err = s.balanceHistoryDB.HasSequence("account1", 1)
+	s.Nil(err)
+	s.False(recorded)
+
+	recorded, err = s.balanceHistoryDB.HasSequence("account2", 2)
+	s.Nil(err)
+	s.False(recorded)
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"balance/pkg/dialect"
+	"database/sql"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so a gateway can run inside
+// a unit of work or on its own.
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// forUpdate returns the clause locking the rows a query reads when it runs in
+// a transaction, so a unit of work can read a row, change it and write it
+// back without another one interleaving. Reads outside one do not lock.
+func forUpdate(db DBTX, d dialect.Dialect) string {
+	if _, ok := db.(*sql.Tx); ok {
+		return d.ForUpdate()
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"errors"
+)
+
+// InboxDB stores the events each consumer has applied. Writing to it in the
+// same transaction as the projection update makes redeliveries detectable.
+type InboxDB struct {
+	DB      DBTX
+	Dialect dialect.Dialect
+}
+
+func NewInboxDB(db DBTX, d dialect.Dialect) *InboxDB {
+	return &InboxDB{
+		DB:      db,
+		Dialect: d,
+	}
+}
+
+func (i *InboxDB) Has(consumer, eventId string) (bool, error) {
+	var found int
+	err := i.DB.QueryRow(
+		i.Dialect.Rebind("SELECT 1 FROM processed_events WHERE consumer = ? AND event_id = ?"), consumer, eventId,
+	).Scan(&found)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (i *InboxDB) Save(processed *entity.ProcessedEvent) error {
+	stmt, err := i.DB.Prepare(i.Dialect.Rebind("INSERT INTO processed_events (consumer, event_id, processed_at) VALUES (?, ?, ?)"))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(processed.Consumer, processed.EventId, normalizeTime(processed.ProcessedAt))
+	return err
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"balance/internal/database"
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+)
+
+type InboxDBTestSuite struct {
+	suite.Suite
+	DB      *sql.DB
+	inboxDB *database.InboxDB
+}
+
+func (s *InboxDBTestSuite) SetupSuite() {
+	db, err := sql.Open("sqlite", ":memory:")
+	s.Nil(err)
+	s.DB = db
+	s.inboxDB = database.NewInboxDB(db, dialect.SQLite)
+	migrateTestDB(s.T(), db)
+}
+
+func (s *InboxDBTestSuite) TearDownSuite() {
+	s.DB.Close()
+}
+
+func (s *InboxDBTestSuite) TearDownTest() {
+	s.DB.Exec("DELETE FROM processed_events")
+}
+
+func TestInboxDBTestSuite(t *testing.T) {
+	suite.Run(t, new(InboxDBTestSuite))
+}
+
+func (s *InboxDBTestSuite) TestSaveAndHas() {
+	processed, err := entity.NewProcessedEvent("account_balances", "balances/0/12")
+	s.Nil(err)
+
+	s.Nil(s.inboxDB.Save(processed))
+
+	found, err := s.inboxDB.Has("account_balances", "balances/0/12")
+	s.Nil(err)
+	s.True(found)
+
+	found, err = s.inboxDB.Has("account_transactions", "balances/0/12")
+	s.Nil(err)
+	s.False(found)
+}
+
+func (s *InboxDBTestSuite) TestSaveRejectsDuplicates() {
+	processed, err := entity.NewProcessedEvent("account_balances", "balances/0/12")
+	s.Nil(err)
+	s.Nil(s.inboxDB.Save(processed))
+
+	s.NotNil(s.inboxDB.Save(processed))
+}
+
+func (s *InboxDBTestSuite) TestRolledBackSaveIsNotRecorded() {
+	tx, err := s.DB.Begin()
+	s.Nil(err)
+	processed, err := entity.NewProcessedEvent("account_balances", "balances/0/13")
+	s.Nil(err)
+	s.Nil(database.NewInboxDB(tx, dialect.SQLite).Save(processed))
+	s.Nil(tx.Rollback())
+
+	found, err := s.inboxDB.Has("account_balances", "balances/0/13")
+	s.Nil(err)
+	s.False(found)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"balance/pkg/dialect"
+	"balance/pkg/migrate"
+	"database/sql"
+	"embed"
+	"io/fs"
+)
+
+// MigrationsTable records the applied migrations. It is named after the
+// service because the wallet service migrates the same database.
+const MigrationsTable = "balance_schema_migrations"
+
+// The scripts in migrations run on MySQL and SQLite; PostgreSQL has its own
+// in migrations/postgres.
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrations embed.FS
+
+// NewMigrator returns a migrator for the schema of the balance service.
+func NewMigrator(db *sql.DB, d dialect.Dialect) (*migrate.Migrator, error) {
+	dir := "migrations"
+	if d == dialect.Postgres {
+		dir = "migrations/postgres"
+	}
+	scripts, err := fs.Sub(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(db, d, MigrationsTable, scripts)
+}
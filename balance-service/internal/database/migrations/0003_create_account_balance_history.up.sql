@@ -0,0 +1,10 @@
+CREATE TABLE account_balance_history (
+    id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    sequence BIGINT NOT NULL,
+    transaction_id VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_account_balance_history_account ON account_balance_history (account_id, occurred_at);
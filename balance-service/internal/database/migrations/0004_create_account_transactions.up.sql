@@ -0,0 +1,12 @@
+CREATE TABLE account_transactions (
+    id VARCHAR(36) PRIMARY KEY,
+    transaction_id VARCHAR(255) NOT NULL,
+    account_id VARCHAR(255) NOT NULL,
+    counterparty_account_id VARCHAR(255) NOT NULL,
+    direction VARCHAR(6) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE (transaction_id, account_id)
+);
+
+CREATE INDEX idx_account_transactions_account ON account_transactions (account_id, created_at);
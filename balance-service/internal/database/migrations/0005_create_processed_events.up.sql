@@ -0,0 +1,7 @@
+-- Inbox of the events each consumer has applied, for idempotent handling
+CREATE TABLE processed_events (
+    consumer VARCHAR(64) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    processed_at DATETIME NOT NULL,
+    PRIMARY KEY (consumer, event_id)
+);
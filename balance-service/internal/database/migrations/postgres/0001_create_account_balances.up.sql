@@ -0,0 +1,5 @@
+-- The schema .docker/init-db created before migrations were tracked
+CREATE TABLE account_balances (
+    account_id VARCHAR(255) PRIMARY KEY,
+    balance DECIMAL(15,2) NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE account_balances DROP COLUMN sequence;
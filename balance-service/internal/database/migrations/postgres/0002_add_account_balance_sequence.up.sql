@@ -0,0 +1,2 @@
+-- Last wallet sequence applied to each balance, so stale updates are skipped
+ALTER TABLE account_balances ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP TABLE processed_events;
@@ -0,0 +1,137 @@
+package database_test
+
+import (
+	"balance/internal/database"
+	"balance/pkg/dialect"
+	"balance/pkg/migrate"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// migrateTestDB creates the production schema in a test database.
+func migrateTestDB(t *testing.T, db *sql.DB) {
+	migrator, err := database.NewMigrator(db, dialect.SQLite)
+	require.Nil(t, err)
+	_, err = migrator.Up(context.Background())
+	require.Nil(t, err)
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, dialect.SQLite)
+	require.Nil(t, err)
+	applied, err := migrator.Up(ctx)
+	require.Nil(t, err)
+	assert.Len(t, applied, len(migrator.Migrations))
+
+	// Every migration can be reverted and applied again
+	reverted, err := migrator.Down(ctx, len(migrator.Migrations))
+	require.Nil(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations))
+	var tables int
+	require.Nil(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name != ?`, database.MigrationsTable).Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	_, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+}
+
+func TestPostgresMigrationsMatch(t *testing.T) {
+	migrator, err := database.NewMigrator(nil, dialect.SQLite)
+	require.Nil(t, err)
+	postgres, err := database.NewMigrator(nil, dialect.Postgres)
+	require.Nil(t, err)
+
+	require.Len(t, postgres.Migrations, len(migrator.Migrations))
+	for i, migration := range migrator.Migrations {
+		assert.Equal(t, migration.Version, postgres.Migrations[i].Version)
+		assert.Equal(t, migration.Name, postgres.Migrations[i].Name)
+	}
+}
+
+// baselineSchema is the balance schema .docker/init-db created before
+// migrations were tracked, with its sample rows.
+const baselineSchema = `-- Balance Service Tables
+CREATE TABLE IF NOT EXISTS account_balances (
+    account_id VARCHAR(255) PRIMARY KEY,
+    balance DECIMAL(15,2) NOT NULL
+);
+
+-- Populate balance service database with matching account balances
+INSERT INTO account_balances (account_id, balance) VALUES
+('7ebc23f5-dd1e-4d93-9490-9fce5052a5f5', 100.00),
+('dff2d137-bba6-4138-81b9-3da7567f122b', 100.00);
+`
+
+// schema describes the columns of every table but the migrations table.
+func schema(t *testing.T, db *sql.DB) []string {
+	rows, err := db.Query(`SELECT m.name, p.name, p.type, p."notnull", p.pk
+		FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name != ?
+		ORDER BY m.name, p.name`, database.MigrationsTable)
+	require.Nil(t, err)
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var table, column, columnType string
+		var notNull, pk int
+		require.Nil(t, rows.Scan(&table, &column, &columnType, &notNull, &pk))
+		columns = append(columns, fmt.Sprintf("%s.%s %s notnull=%d pk=%d", table, column, columnType, notNull, pk))
+	}
+	require.Nil(t, rows.Err())
+	return columns
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrationsAdoptBaseline(t *testing.T) {
+	ctx := context.Background()
+	baseline := openTestDB(t)
+	for _, statement := range migrate.Statements(baselineSchema) {
+		_, err := baseline.Exec(statement)
+		require.Nil(t, err)
+	}
+
+	// The first migration creates exactly the baseline
+	fresh := openTestDB(t)
+	migrator, err := database.NewMigrator(fresh, dialect.SQLite)
+	require.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	require.Nil(t, err)
+	wantSchema := schema(t, fresh)
+	_, err = migrator.Down(ctx, len(migrator.Migrations)-1)
+	require.Nil(t, err)
+	assert.Equal(t, schema(t, baseline), schema(t, fresh))
+
+	// force 1 then up brings the baseline to the current schema, keeping its rows
+	migrator, err = database.NewMigrator(baseline, dialect.SQLite)
+	require.Nil(t, err)
+	require.Nil(t, migrator.Force(ctx, 1))
+	applied, err := migrator.Up(ctx)
+	require.Nil(t, err)
+	assert.Len(t, applied, len(migrator.Migrations)-1)
+	assert.Equal(t, wantSchema, schema(t, baseline))
+
+	var balance float64
+	var sequence int64
+	require.Nil(t, baseline.QueryRow(`SELECT balance, sequence FROM account_balances WHERE account_id = ?`, database.SampleAccountA).Scan(&balance, &sequence))
+	assert.Equal(t, 100.0, balance)
+	assert.Equal(t, int64(0), sequence)
+}
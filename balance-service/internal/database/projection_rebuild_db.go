@@ -0,0 +1,126 @@
+package database
+
+import (
+	"balance/pkg/dialect"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ShadowSuffix is appended to a projection table name to get the table a
+// rebuild writes to before it is swapped in.
+const ShadowSuffix = "_rebuild"
+
+// ShadowTable describes one projection table taking part in a rebuild. The
+// gateways of a projection take their table name through their WithTable
+// constructors, so a rebuild writes the shadow with the same code that writes
+// the live table.
+type ShadowTable struct {
+	Name string
+	// Seed selects the live rows copied into the shadow before the replay
+	// starts, in the table's column order. Empty leaves the shadow empty.
+	Seed     string
+	SeedArgs []interface{}
+}
+
+// ShadowName returns the name of the shadow copy of the table.
+func (t ShadowTable) ShadowName() string {
+	return t.Name + ShadowSuffix
+}
+
+type ProjectionRebuildDB struct {
+	DB      *sql.DB
+	Dialect dialect.Dialect
+}
+
+func NewProjectionRebuildDB(db *sql.DB, d dialect.Dialect) *ProjectionRebuildDB {
+	return &ProjectionRebuildDB{
+		DB:      db,
+		Dialect: d,
+	}
+}
+
+// Prepare (re)creates the shadow tables with the columns, keys and unique
+// constraints of the live tables, so a replay cannot insert rows the live
+// tables would refuse, and seeds them. Shadows left behind by an aborted
+// rebuild are dropped first.
+func (p *ProjectionRebuildDB) Prepare(tables []ShadowTable) error {
+	for _, table := range tables {
+		if _, err := p.DB.Exec("DROP TABLE IF EXISTS " + table.ShadowName()); err != nil {
+			return err
+		}
+		if err := p.createShadow(table); err != nil {
+			return fmt.Errorf("creating %s: %w", table.ShadowName(), err)
+		}
+		if table.Seed == "" {
+			continue
+		}
+		_, err := p.DB.Exec(p.Dialect.Rebind(fmt.Sprintf("INSERT INTO %s %s", table.ShadowName(), table.Seed)), table.SeedArgs...)
+		if err != nil {
+			return fmt.Errorf("seeding %s: %w", table.ShadowName(), err)
+		}
+	}
+	return nil
+}
+
+// createShadow creates the shadow of a table like the live one. SQLite has no
+// CREATE TABLE ... LIKE, so the live table's own DDL is reused; its separate
+// indexes are left out, the shadow is only written and then copied.
+func (p *ProjectionRebuildDB) createShadow(table ShadowTable) error {
+	switch p.Dialect {
+	case dialect.MySQL:
+		_, err := p.DB.Exec(fmt.Sprintf("CREATE TABLE %s LIKE %s", table.ShadowName(), table.Name))
+		return err
+	case dialect.Postgres:
+		_, err := p.DB.Exec(fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL)", table.ShadowName(), table.Name))
+		return err
+	default:
+		var ddl string
+		err := p.DB.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table.Name).Scan(&ddl)
+		if err != nil {
+			return err
+		}
+		_, err = p.DB.Exec(strings.Replace(ddl, table.Name, table.ShadowName(), 1))
+		return err
+	}
+}
+
+// Fence keeps the live consumer from writing to the live tables for the rest
+// of tx. A rebuild fences the tables, replays what the live consumer handled
+// since the replay ended into the shadows and swaps them in, all in tx, so
+// nothing the live consumer commits is lost and everything it handles after
+// the swap applies on top of the rebuilt projection.
+func (p *ProjectionRebuildDB) Fence(tx *sql.Tx, tables []ShadowTable) error {
+	for _, table := range tables {
+		if _, err := tx.Exec(p.Dialect.LockTable(table.Name)); err != nil {
+			return fmt.Errorf("fencing %s: %w", table.Name, err)
+		}
+	}
+	return nil
+}
+
+// Swap replaces the contents of every live table with its shadow in tx, so
+// readers see either the old or the rebuilt projection. Fence the tables in
+// tx first. The shadows are left for Discard.
+func (p *ProjectionRebuildDB) Swap(tx *sql.Tx, tables []ShadowTable) error {
+	for _, table := range tables {
+		if _, err := tx.Exec("DELETE FROM " + table.Name); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", table.Name, table.ShadowName()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discard drops the shadow tables without touching the live ones.
+func (p *ProjectionRebuildDB) Discard(tables []ShadowTable) error {
+	for _, table := range tables {
+		if _, err := p.DB.Exec("DROP TABLE IF EXISTS " + table.ShadowName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package database_test
+
+import (
+	"balance/internal/database"
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+)
+
+type ProjectionRebuildDBTestSuite struct {
+	suite.Suite
+	DB                  *sql.DB
+	projectionRebuildDB *database.ProjectionRebuildDB
+	tables              []database.ShadowTable
+}
+
+func (s *ProjectionRebuildDBTestSuite) SetupTest() {
+	db, err := sql.Open("sqlite", ":memory:")
+	s.Nil(err)
+	s.DB = db
+	s.projectionRebuildDB = database.NewProjectionRebuildDB(db, dialect.SQLite)
+	s.tables = []database.ShadowTable{
+		{Name: "account_balances", Seed: "SELECT account_id, balance, 0 FROM account_balances"},
+		{Name: "account_balance_history", Seed: "SELECT * FROM account_balance_history WHERE sequence = 0"},
+	}
+	migrateTestDB(s.T(), db)
+}
+
+func (s *ProjectionRebuildDBTestSuite) TearDownTest() {
+	s.DB.Close()
+}
+
+func TestProjectionRebuildDBTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectionRebuildDBTestSuite))
+}
+
+func (s *ProjectionRebuildDBTestSuite) seedLive() {
+	balanceDB := database.NewBalanceDB(s.DB, dialect.SQLite)
+	s.Nil(balanceDB.Save(&entity.AccountBalance{AccountId: "account1", Balance: 999, Sequence: 7}))
+
+	historyDB := database.NewBalanceHistoryDB(s.DB, dialect.SQLite)
+	opening, err := entity.NewBalanceChange("account1", 100, 0, "", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	s.Nil(err)
+	s.Nil(historyDB.Save(opening))
+	wrong, err := entity.NewBalanceChange("account1", 999, 7, "tx-7", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	s.Nil(err)
+	s.Nil(historyDB.Save(wrong))
+}
+
+func (s *ProjectionRebuildDBTestSuite) count(table string) int {
+	var n int
+	s.Nil(s.DB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n))
+	return n
+}
+
+func (s *ProjectionRebuildDBTestSuite) TestPrepareSeedsShadowTables() {
+	s.seedLive()
+
+	s.Nil(s.projectionRebuildDB.Prepare(s.tables))
+
+	shadow, err := database.NewBalanceDBWithTable(s.DB, dialect.SQLite, "account_balances_rebuild").FindById("account1")
+	s.Nil(err)
+	s.Equal(999.0, shadow.Balance)
+	s.Equal(int64(0), shadow.Sequence)
+	s.Equal(1, s.count("account_balance_history_rebuild"))
+	s.Equal(2, s.count("account_balance_history"))
+}
+
+func (s *ProjectionRebuildDBTestSuite) TestPrepareReplacesLeftoverShadow() {
+	s.seedLive()
+	s.Nil(s.projectionRebuildDB.Prepare(s.tables))
+	_, err := s.DB.Exec("DELETE FROM account_balances_rebuild")
+	s.Nil(err)
+
+	s.Nil(s.projectionRebuildDB.Prepare(s.tables))
+
+	s.Equal(1, s.count("account_balances_rebuild"))
+}
+
+func (s *ProjectionRebuildDBTestSuite) TestPrepareKeepsKeys() {
+	s.seedLive()
+	s.Nil(s.projectionRebuildDB.Prepare(s.tables))
+
+	shadow := database.NewBalanceDBWithTable(s.DB, dialect.SQLite, "account_balances_rebuild")
+	s.Error(shadow.Save(&entity.AccountBalance{AccountId: "account1", Balance: 1}))
+}
+
+func (s *ProjectionRebuildDBTestSuite) TestSwapReplacesLiveTables() {
+	s.seedLive()
+	s.Nil(s.projectionRebuildDB.Prepare(s.tables))
+
+	shadowBalances := database.NewBalanceDBWithTable(s.DB, dialect.SQLite, "account_balances_rebuild")
+	s.Nil(shadowBalances.UpdateBalance(&entity.AccountBalance{AccountId: "account1", Balance: 150, Sequence: 7}))
+	shadowHistory := database.NewBalanceHistoryDBWithTable(s.DB, dialect.SQLite, "account_balance_history_rebuild")
+	change, err := entity.NewBalanceChange("account1", 150, 7, "tx-7", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	s.Nil(err)
+	s.Nil(shadowHistory.Save(change))
+
+	tx, err := s.DB.Begin()
+	s.Nil(err)
+	s.Nil(s.projectionRebuildDB.Fence(tx, s.tables))
+	s.Nil(s.projectionRebuildDB.Swap(tx, s.tables))
+	s.Nil(tx.Commit())
+	s.Nil(s.projectionRebuildDB.Discard(s.tables))
+
+	live, err := database.NewBalanceDB(s.DB, dialect.SQLite).FindById("account1")
+	s.Nil(err)
+	s.Equal(150.0, live.Balance)
+	s.Equal(int64(7), live.Sequence)
+
+	changes, err := database.NewBalanceHistoryDB(s.DB, dialect.SQLite).FindByAccountId("account1", 10, 0)
+	s.Nil(err)
+	s.Len(changes, 2)
+	s.Equal(150.0, changes[0].Balance)
+	s.Equal(100.0, changes[1].Balance)
+
+	var name string
+	err = s.DB.QueryRow("SELECT name FROM sqlite_master WHERE name = 'account_balances_rebuild'").Scan(&name)
+	s.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (s *ProjectionRebuildDBTestSuite) TestDiscardKeepsLiveTables() {
+	s.seedLive()
+	s.Nil(s.projectionRebuildDB.Prepare(s.tables))
+
+	s.Nil(s.projectionRebuildDB.Discard(s.tables))
+
+	live, err := database.NewBalanceDB(s.DB, dialect.SQLite).FindById("account1")
+	s.Nil(err)
+	s.Equal(999.0, live.Balance)
+	s.Equal(2, s.count("account_balance_history"))
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"balance/pkg/dialect"
+	"database/sql"
+	"time"
+)
+
+// Sample accounts used by the README and api/client.http, matching the ones
+// the wallet service seeds.
+const (
+	SampleAccountA = "7ebc23f5-dd1e-4d93-9490-9fce5052a5f5"
+	SampleAccountB = "dff2d137-bba6-4138-81b9-3da7567f122b"
+)
+
+// Seed inserts the balances of the sample accounts, 100 each, with their
+// opening balance in the history. Rows that already exist are left alone,
+// so it is safe to run on every start.
+func Seed(db *sql.DB, d dialect.Dialect) error {
+	now := normalizeTime(time.Now())
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	err = seedRows(tx, d.Upsert("account_balances", []string{"account_id", "balance", "sequence"}, []string{"account_id"}, nil), [][]interface{}{
+		{SampleAccountA, 100.0, 0},
+		{SampleAccountB, 100.0, 0},
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	history := []string{"id", "account_id", "balance", "sequence", "transaction_id", "occurred_at"}
+	err = seedRows(tx, d.Upsert("account_balance_history", history, []string{"id"}, nil), [][]interface{}{
+		{"0d0c6c55-8a44-4ad4-9e41-0b6a4f0d8c01", SampleAccountA, 100.0, 0, "", now},
+		{"5b1f8e0a-3f0d-4a8e-b8a4-6f3c2f2e9d02", SampleAccountB, 100.0, 0, "", now},
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func seedRows(tx *sql.Tx, query string, rows [][]interface{}) error {
+	for _, row := range rows {
+		if _, err := tx.Exec(query, row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
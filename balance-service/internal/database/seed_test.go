@@ -0,0 +1,33 @@
+package database_test
+
+import (
+	"balance/internal/database"
+	"balance/internal/entity"
+	"balance/pkg/dialect"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	migrateTestDB(t, db)
+
+	require.Nil(t, database.Seed(db, dialect.SQLite))
+	require.Nil(t, database.NewBalanceDB(db, dialect.SQLite).UpdateBalance(&entity.AccountBalance{AccountId: database.SampleAccountA, Balance: 90, Sequence: 1}))
+	// Seeding again leaves existing rows alone
+	require.Nil(t, database.Seed(db, dialect.SQLite))
+
+	balance, err := database.NewBalanceDB(db, dialect.SQLite).FindById(database.SampleAccountA)
+	require.Nil(t, err)
+	assert.Equal(t, 90.0, balance.Balance)
+	history, err := database.NewBalanceHistoryDB(db, dialect.SQLite).FindByAccountId(database.SampleAccountB, 10, 0)
+	require.Nil(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 100.0, history[0].Balance)
+}
@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DirectionDebit  = "debit"
+	DirectionCredit = "credit"
+)
+
+const (
+	ErrInvalidTransaction = "invalid transaction"
+	ErrInvalidAmount      = "invalid amount"
+	ErrInvalidDirection   = "invalid direction"
+)
+
+// AccountTransaction is one side of a transfer, as seen by a single account.
+type AccountTransaction struct {
+	Id                    string    `json:"id"`
+	TransactionId         string    `json:"transaction_id"`
+	AccountId             string    `json:"account_id"`
+	CounterpartyAccountId string    `json:"counterparty_account_id"`
+	Direction             string    `json:"direction"`
+	Amount                float64   `json:"amount"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+func NewAccountTransaction(
+	transactionId string,
+	accountId string,
+	counterpartyAccountId string,
+	direction string,
+	amount float64,
+	createdAt time.Time,
+) (*AccountTransaction, error) {
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	accountTransaction := &AccountTransaction{
+		Id:                    uuid.New().String(),
+		TransactionId:         transactionId,
+		AccountId:             accountId,
+		CounterpartyAccountId: counterpartyAccountId,
+		Direction:             direction,
+		Amount:                amount,
+		CreatedAt:             createdAt,
+	}
+
+	if err := accountTransaction.Validate(); err != nil {
+		return nil, err
+	}
+
+	return accountTransaction, nil
+}
+
+func (t *AccountTransaction) Validate() error {
+	if t.TransactionId == "" {
+		return errors.New(ErrInvalidTransaction)
+	}
+	if t.AccountId == "" || t.CounterpartyAccountId == "" {
+		return errors.New(ErrInvalidClient)
+	}
+	if t.Amount <= 0 {
+		return errors.New(ErrInvalidAmount)
+	}
+	if t.Direction != DirectionDebit && t.Direction != DirectionCredit {
+		return errors.New(ErrInvalidDirection)
+	}
+	return nil
+}
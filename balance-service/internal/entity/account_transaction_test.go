@@ -0,0 +1,46 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"balance/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAccountTransaction(t *testing.T) {
+	t.Run("should create a new account transaction", func(t *testing.T) {
+		createdAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+		accountTransaction, err := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 10.0, createdAt)
+
+		assert.Nil(t, err)
+		assert.NotEmpty(t, accountTransaction.Id)
+		assert.Equal(t, "tx1", accountTransaction.TransactionId)
+		assert.Equal(t, "account1", accountTransaction.AccountId)
+		assert.Equal(t, "account2", accountTransaction.CounterpartyAccountId)
+		assert.Equal(t, entity.DirectionDebit, accountTransaction.Direction)
+		assert.Equal(t, 10.0, accountTransaction.Amount)
+		assert.Equal(t, createdAt, accountTransaction.CreatedAt)
+	})
+
+	t.Run("should return error when transaction id is empty", func(t *testing.T) {
+		_, err := entity.NewAccountTransaction("", "account1", "account2", entity.DirectionDebit, 10.0, time.Now())
+		assert.Equal(t, entity.ErrInvalidTransaction, err.Error())
+	})
+
+	t.Run("should return error when an account is empty", func(t *testing.T) {
+		_, err := entity.NewAccountTransaction("tx1", "account1", "", entity.DirectionDebit, 10.0, time.Now())
+		assert.Equal(t, entity.ErrInvalidClient, err.Error())
+	})
+
+	t.Run("should return error when amount is not positive", func(t *testing.T) {
+		_, err := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 0, time.Now())
+		assert.Equal(t, entity.ErrInvalidAmount, err.Error())
+	})
+
+	t.Run("should return error when direction is unknown", func(t *testing.T) {
+		_, err := entity.NewAccountTransaction("tx1", "account1", "account2", "sideways", 10.0, time.Now())
+		assert.Equal(t, entity.ErrInvalidDirection, err.Error())
+	})
+}
@@ -5,6 +5,7 @@ import "errors"
 type AccountBalance struct {
 	AccountId string  `json:"account_id"`
 	Balance   float64 `json:"balance"`
+	Sequence  int64   `json:"sequence"`
 }
 
 const (
@@ -43,3 +44,28 @@ func (b *AccountBalance) UpdateBalance(newBalance float64) error {
 	b.Balance = newBalance
 	return nil
 }
+
+// IsStale reports whether an update carrying the given sequence was already
+// applied. Updates without a sequence (0) predate sequencing and are never stale.
+func (b *AccountBalance) IsStale(sequence int64) bool {
+	return sequence != 0 && sequence <= b.Sequence
+}
+
+// Gap returns how many updates are missing between the last applied
+// sequence and the given one.
+func (b *AccountBalance) Gap(sequence int64) int64 {
+	if sequence == 0 || sequence <= b.Sequence+1 {
+		return 0
+	}
+	return sequence - b.Sequence - 1
+}
+
+func (b *AccountBalance) ApplyUpdate(newBalance float64, sequence int64) error {
+	if err := b.UpdateBalance(newBalance); err != nil {
+		return err
+	}
+	if sequence != 0 {
+		b.Sequence = sequence
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BalanceChange struct {
+	Id            string    `json:"id"`
+	AccountId     string    `json:"account_id"`
+	Balance       float64   `json:"balance"`
+	Sequence      int64     `json:"sequence"`
+	TransactionId string    `json:"transaction_id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+func NewBalanceChange(accountId string, balance float64, sequence int64, transactionId string, occurredAt time.Time) (*BalanceChange, error) {
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	change := &BalanceChange{
+		Id:            uuid.New().String(),
+		AccountId:     accountId,
+		Balance:       balance,
+		Sequence:      sequence,
+		TransactionId: transactionId,
+		OccurredAt:    occurredAt,
+	}
+
+	if err := change.Validate(); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (c *BalanceChange) Validate() error {
+	if c.AccountId == "" {
+		return errors.New(ErrInvalidClient)
+	}
+	if c.Balance < 0 {
+		return errors.New(ErrInsufficientBalance)
+	}
+	return nil
+}
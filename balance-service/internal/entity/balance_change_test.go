@@ -0,0 +1,46 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"balance/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBalanceChange(t *testing.T) {
+	t.Run("should create a new balance change", func(t *testing.T) {
+		occurredAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+		change, err := entity.NewBalanceChange("account1", 90.0, 2, "tx1", occurredAt)
+
+		assert.Nil(t, err)
+		assert.NotEmpty(t, change.Id)
+		assert.Equal(t, "account1", change.AccountId)
+		assert.Equal(t, 90.0, change.Balance)
+		assert.Equal(t, int64(2), change.Sequence)
+		assert.Equal(t, "tx1", change.TransactionId)
+		assert.Equal(t, occurredAt, change.OccurredAt)
+	})
+
+	t.Run("should default occurred at to now", func(t *testing.T) {
+		change, err := entity.NewBalanceChange("account1", 90.0, 2, "tx1", time.Time{})
+
+		assert.Nil(t, err)
+		assert.False(t, change.OccurredAt.IsZero())
+	})
+
+	t.Run("should return error when account id is empty", func(t *testing.T) {
+		change, err := entity.NewBalanceChange("", 90.0, 2, "tx1", time.Now())
+
+		assert.Nil(t, change)
+		assert.Equal(t, entity.ErrInvalidClient, err.Error())
+	})
+
+	t.Run("should return error when balance is negative", func(t *testing.T) {
+		change, err := entity.NewBalanceChange("account1", -1.0, 2, "tx1", time.Now())
+
+		assert.Nil(t, change)
+		assert.Equal(t, entity.ErrInsufficientBalance, err.Error())
+	})
+}
@@ -52,3 +52,43 @@ func TestNewBalance(t *testing.T) {
 		assert.Equal(t, entity.ErrInsufficientBalance, err.Error())
 	})
 }
+
+func TestAccountBalanceSequence(t *testing.T) {
+	t.Run("should apply update and advance sequence", func(t *testing.T) {
+		balance, _ := entity.NewBalance("account1", 100.0)
+
+		err := balance.ApplyUpdate(90.0, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 90.0, balance.Balance)
+		assert.Equal(t, int64(1), balance.Sequence)
+	})
+
+	t.Run("should keep sequence when update has no sequence", func(t *testing.T) {
+		balance, _ := entity.NewBalance("account1", 100.0)
+		balance.Sequence = 3
+
+		err := balance.ApplyUpdate(90.0, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 90.0, balance.Balance)
+		assert.Equal(t, int64(3), balance.Sequence)
+	})
+
+	t.Run("should detect stale and duplicate updates", func(t *testing.T) {
+		balance, _ := entity.NewBalance("account1", 100.0)
+		balance.Sequence = 3
+
+		assert.True(t, balance.IsStale(2))
+		assert.True(t, balance.IsStale(3))
+		assert.False(t, balance.IsStale(4))
+		assert.False(t, balance.IsStale(0))
+	})
+
+	t.Run("should report gaps between sequences", func(t *testing.T) {
+		balance, _ := entity.NewBalance("account1", 100.0)
+		balance.Sequence = 3
+
+		assert.Equal(t, int64(0), balance.Gap(4))
+		assert.Equal(t, int64(2), balance.Gap(6))
+		assert.Equal(t, int64(0), balance.Gap(0))
+	})
+}
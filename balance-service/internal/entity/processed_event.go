@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ErrInvalidConsumer = "invalid consumer"
+	ErrInvalidEventId  = "invalid event id"
+)
+
+// ProcessedEvent marks an event a consumer has already applied, so a
+// redelivery of the same event can be recognized and skipped.
+type ProcessedEvent struct {
+	Consumer    string    `json:"consumer"`
+	EventId     string    `json:"event_id"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+func NewProcessedEvent(consumer, eventId string) (*ProcessedEvent, error) {
+	processed := &ProcessedEvent{
+		Consumer:    consumer,
+		EventId:     eventId,
+		ProcessedAt: time.Now(),
+	}
+
+	if err := processed.Validate(); err != nil {
+		return nil, err
+	}
+
+	return processed, nil
+}
+
+func (p *ProcessedEvent) Validate() error {
+	if p.Consumer == "" {
+		return errors.New(ErrInvalidConsumer)
+	}
+	if p.EventId == "" {
+		return errors.New(ErrInvalidEventId)
+	}
+	return nil
+}
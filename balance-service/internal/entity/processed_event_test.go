@@ -0,0 +1,34 @@
+package entity_test
+
+import (
+	"testing"
+
+	"balance/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProcessedEvent(t *testing.T) {
+	t.Run("should create a new processed event", func(t *testing.T) {
+		processed, err := entity.NewProcessedEvent("account_balances", "balances/0/12")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "account_balances", processed.Consumer)
+		assert.Equal(t, "balances/0/12", processed.EventId)
+		assert.False(t, processed.ProcessedAt.IsZero())
+	})
+
+	t.Run("should return error when consumer is empty", func(t *testing.T) {
+		processed, err := entity.NewProcessedEvent("", "balances/0/12")
+
+		assert.Nil(t, processed)
+		assert.Equal(t, entity.ErrInvalidConsumer, err.Error())
+	})
+
+	t.Run("should return error when event id is empty", func(t *testing.T) {
+		processed, err := entity.NewProcessedEvent("account_balances", "")
+
+		assert.Nil(t, processed)
+		assert.Equal(t, entity.ErrInvalidEventId, err.Error())
+	})
+}
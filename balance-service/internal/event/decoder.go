@@ -0,0 +1,48 @@
+package event
+
+import (
+	"balance/pkg/events"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var factories = map[string]func(id string, dateTime time.Time, payload interface{}) events.EventInterface{
+	"BalanceUpdated": func(id string, dateTime time.Time, payload interface{}) events.EventInterface {
+		return ReceivedBalanceUpdated(id, dateTime, payload)
+	},
+	"TransactionCreated": func(id string, dateTime time.Time, payload interface{}) events.EventInterface {
+		return ReceivedTransactionCreated(id, dateTime, payload)
+	},
+}
+
+// Decode turns a raw message value and its headers into the event it carries,
+// picking the concrete type from the event name, and checks it against the
+// schema of the event. Binary and structured CloudEvents are accepted as well
+// as events published before CloudEvents. A CloudEvent keeps its id and time;
+// an older event, which has neither, gets new ones.
+func Decode(headers map[string]string, data []byte) (events.EventInterface, error) {
+	attributes, payload, err := events.DecodeMessage(headers, data)
+	if err != nil {
+		return nil, err
+	}
+
+	factory, ok := factories[attributes.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown event %q", attributes.Name)
+	}
+	id, dateTime := attributes.ID, attributes.Time
+	if id == "" {
+		id = uuid.New().String()
+	}
+	if dateTime.IsZero() {
+		dateTime = time.Now()
+	}
+	decoded := factory(id, dateTime, payload)
+
+	if err := events.ValidateEvent(decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
@@ -0,0 +1,86 @@
+package event_test
+
+import (
+	"balance/internal/event"
+	"balance/pkg/events"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	t.Run("should decode a balance updated event", func(t *testing.T) {
+		decoded, err := event.Decode(nil, []byte(`{"name":"BalanceUpdated","payload":{"account_id":"a1","balance":90}}`))
+
+		assert.Nil(t, err)
+		assert.IsType(t, &event.BalanceUpdated{}, decoded)
+		assert.Equal(t, "BalanceUpdated", decoded.GetName())
+		assert.Equal(t, "a1", decoded.GetPayload().(map[string]interface{})["account_id"])
+		assert.NotEmpty(t, decoded.GetID())
+	})
+
+	t.Run("should decode a transaction created event", func(t *testing.T) {
+		decoded, err := event.Decode(nil, []byte(`{"name":"TransactionCreated","payload":{"id":"tx1","account_id_from":"a1","account_id_to":"a2","amount":10,"created_at":"2025-03-03T10:00:00Z"}}`))
+
+		assert.Nil(t, err)
+		assert.IsType(t, &event.TransactionCreated{}, decoded)
+		assert.Equal(t, "tx1", decoded.GetPayload().(map[string]interface{})["id"])
+	})
+
+	t.Run("should return error for unknown events", func(t *testing.T) {
+		decoded, err := event.Decode(nil, []byte(`{"name":"AccountClosed","payload":{}}`))
+
+		assert.Nil(t, decoded)
+		assert.EqualError(t, err, `unknown event "AccountClosed"`)
+	})
+
+	t.Run("should return error for events that do not match their schema", func(t *testing.T) {
+		decoded, err := event.Decode(nil, []byte(`{"name":"BalanceUpdated","payload":{"account_id":"a1","balance":"ninety"}}`))
+
+		assert.Nil(t, decoded)
+		assert.ErrorContains(t, err, "BalanceUpdated does not match its schema")
+	})
+
+	t.Run("should return error for malformed messages", func(t *testing.T) {
+		decoded, err := event.Decode(nil, []byte(`not json`))
+
+		assert.Nil(t, decoded)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	balanceUpdated := event.NewBalanceUpdated(map[string]interface{}{"account_id": "a1", "balance": 90.25, "sequence": 3})
+	data, err := events.ProtobufCodec{}.Marshal(balanceUpdated)
+	require.Nil(t, err)
+
+	decoded, err := event.Decode(map[string]string{events.HeaderContentType: events.ContentTypeProtobuf}, data)
+
+	require.Nil(t, err)
+	assert.IsType(t, &event.BalanceUpdated{}, decoded)
+	payload := decoded.GetPayload().(map[string]interface{})
+	assert.Equal(t, "a1", payload["account_id"])
+	assert.Equal(t, json.Number("90.25"), payload["balance"])
+
+	_, err = event.Decode(map[string]string{events.HeaderContentType: "application/xml"}, data)
+	assert.EqualError(t, err, `unsupported content type "application/xml"`)
+}
+
+func TestDecodeCloudEvents(t *testing.T) {
+	balanceUpdated := event.NewBalanceUpdated(map[string]interface{}{"account_id": "a1", "balance": 90})
+
+	for _, structured := range []bool{false, true} {
+		value, headers, err := events.NewCloudEventsEncoder("/wallet-service", structured, events.JSONCodec{}).Encode(balanceUpdated)
+		require.Nil(t, err)
+
+		decoded, err := event.Decode(headers, value)
+
+		require.Nil(t, err)
+		assert.IsType(t, &event.BalanceUpdated{}, decoded)
+		assert.Equal(t, "a1", decoded.GetPayload().(map[string]interface{})["account_id"])
+		assert.Equal(t, balanceUpdated.GetID(), decoded.GetID())
+		assert.True(t, balanceUpdated.GetDateTime().Equal(decoded.GetDateTime()))
+	}
+}
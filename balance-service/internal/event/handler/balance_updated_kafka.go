@@ -3,17 +3,38 @@ package handler
 import (
 	"balance/internal/usecase/update_account_balance"
 	"balance/pkg/events"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
 	"log"
-	"sync"
+	"time"
 )
 
+// BalanceUpdatedPayload carries the new balance of one account. Events
+// published before per-account events carry both sides of a transfer in the
+// *_from and *_to fields instead.
 type BalanceUpdatedPayload struct {
-	AccountIdFrom        string  `json:"account_id_from"`
-	AccountIdTo          string  `json:"account_id_to"`
-	BalanceAccountIdFrom float64 `json:"balance_account_id_from"`
-	BalanceAccountIdTo   float64 `json:"balance_account_id_to"`
+	AccountId             string    `json:"account_id"`
+	Balance               float64   `json:"balance"`
+	Sequence              int64     `json:"sequence"`
+	AccountIdFrom         string    `json:"account_id_from"`
+	AccountIdTo           string    `json:"account_id_to"`
+	BalanceAccountIdFrom  float64   `json:"balance_account_id_from"`
+	BalanceAccountIdTo    float64   `json:"balance_account_id_to"`
+	SequenceAccountIdFrom int64     `json:"sequence_account_id_from"`
+	SequenceAccountIdTo   int64     `json:"sequence_account_id_to"`
+	TransactionId         string    `json:"transaction_id"`
+	CreatedAt             time.Time `json:"created_at"`
 }
 
+// SequenceGaps counts the balance updates found missing between the sequences
+// of consecutive events for an account, served on /debug/vars. The newer
+// balance is still applied, since it is absolute; the count flags events the
+// wallet published but never reached the projection.
+var SequenceGaps = expvar.NewInt("balance_sequence_gaps")
+
 type BalanceUpdatedKafkaHandler struct {
 	UpdateBalanceUseCase *update_account_balance.UpdateAccountBalanceUseCase
 }
@@ -26,46 +47,61 @@ func NewBalanceUpdatedKafkaHandler(
 	}
 }
 
-func (h *BalanceUpdatedKafkaHandler) Handle(message events.EventInterface, wg *sync.WaitGroup) {
-	defer wg.Done()
+func (h *BalanceUpdatedKafkaHandler) Handle(ctx context.Context, message events.EventInterface) error {
+	return h.Process(ctx, message)
+}
 
+// Process applies the balances carried by the event and reports failures so
+// the consumer can retry or dead-letter the message.
+func (h *BalanceUpdatedKafkaHandler) Process(ctx context.Context, message events.EventInterface) error {
 	if message.GetName() != "BalanceUpdated" {
-		log.Print("Received message with wrong event name")
-		return
+		return fmt.Errorf("received message with wrong event name %q", message.GetName())
 	}
 
-	msgPayload, ok := message.GetPayload().(map[string]interface{})
-	if !ok {
-		log.Print("Failed to cast message payload to map[string]interface{}")
-		return
+	// Round-trip the generic payload into the typed one
+	raw, err := json.Marshal(message.GetPayload())
+	if err != nil {
+		return fmt.Errorf("failed to encode balance payload: %w", err)
+	}
+	var payload BalanceUpdatedPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return fmt.Errorf("failed to decode balance payload: %w", err)
 	}
 
-	payload := &BalanceUpdatedPayload{
-		AccountIdFrom:        msgPayload["account_id_from"].(string),
-		AccountIdTo:          msgPayload["account_id_to"].(string),
-		BalanceAccountIdFrom: msgPayload["balance_account_id_from"].(float64),
-		BalanceAccountIdTo:   msgPayload["balance_account_id_to"].(float64),
+	if payload.AccountId != "" {
+		return h.updateBalance(ctx, &payload, payload.AccountId, payload.Balance, payload.Sequence)
+	}
+	if payload.AccountIdFrom == "" || payload.AccountIdTo == "" {
+		return errors.New("balance payload carries no account")
+	}
+	// Sequences are absent from events published before sequencing was introduced
+	if err := h.updateBalance(ctx, &payload, payload.AccountIdFrom, payload.BalanceAccountIdFrom, payload.SequenceAccountIdFrom); err != nil {
+		return err
 	}
+	return h.updateBalance(ctx, &payload, payload.AccountIdTo, payload.BalanceAccountIdTo, payload.SequenceAccountIdTo)
+}
 
+func (h *BalanceUpdatedKafkaHandler) updateBalance(ctx context.Context, payload *BalanceUpdatedPayload, accountID string, balance float64, sequence int64) error {
 	input := update_account_balance.UpdateAccountBalanceInputDTO{
-		AccountID: payload.AccountIdFrom,
-		Balance:   payload.BalanceAccountIdFrom,
+		AccountID:     accountID,
+		Balance:       balance,
+		Sequence:      sequence,
+		TransactionID: payload.TransactionId,
+		OccurredAt:    payload.CreatedAt,
+		EventID:       EventID(ctx),
 	}
-	output, err := h.UpdateBalanceUseCase.Execute(input)
+	output, err := h.UpdateBalanceUseCase.Execute(ctx, input)
 	if err != nil {
-		log.Printf("Failed to update balance for account %s: %v", payload.AccountIdFrom, err)
-		return
+		return fmt.Errorf("failed to update balance for account %s: %w", accountID, err)
 	}
-	log.Printf("Updated balance for account %s: %f\n", output.AccountID, output.Balance)
-
-	input2 := update_account_balance.UpdateAccountBalanceInputDTO{
-		AccountID: payload.AccountIdTo,
-		Balance:   payload.BalanceAccountIdTo,
+	if output.Skipped {
+		log.Printf("Skipped stale or redelivered update for account %s at sequence %d\n", accountID, sequence)
+		return nil
 	}
-	output2, err := h.UpdateBalanceUseCase.Execute(input2)
-	if err != nil {
-		log.Printf("Failed to update balance for account %s: %v", payload.AccountIdTo, err)
-		return
+	if output.Gap > 0 {
+		SequenceGaps.Add(output.Gap)
+		log.Printf("WARNING: sequence gap for account %s: %d update(s) missing before sequence %d\n", accountID, output.Gap, sequence)
 	}
-	log.Printf("Updated balance for account %s: %f\n", output2.AccountID, output2.Balance)
+	log.Printf("Updated balance for account %s: %f\n", output.AccountID, output.Balance)
+	return nil
 }
@@ -0,0 +1,102 @@
+package handler_test
+
+import (
+	"balance/internal/entity"
+	"balance/internal/event"
+	"balance/internal/event/handler"
+	"balance/internal/gateway"
+	"balance/internal/usecase/mocks"
+	"balance/internal/usecase/update_account_balance"
+	"balance/pkg/uow"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newBalanceUpdatedHandler(balanceMock *mocks.BalanceGatewayMock) *handler.BalanceUpdatedKafkaHandler {
+	historyMock := &mocks.BalanceHistoryGatewayMock{}
+	historyMock.On("Save", mock.Anything).Return(nil)
+	uowMock := &mocks.UowMock{}
+	uowMock.On("Do", mock.Anything, mock.Anything).Return(nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.BalanceGateway]()).Return(balanceMock, nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.BalanceHistoryGateway]()).Return(historyMock, nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.InboxGateway]()).Return(&mocks.InboxGatewayMock{}, nil)
+	return handler.NewBalanceUpdatedKafkaHandler(update_account_balance.NewUpdateAccountBalanceUseCase(uowMock))
+}
+
+func newBalanceUpdated(payload map[string]interface{}) *event.BalanceUpdated {
+	return event.NewBalanceUpdated(payload)
+}
+
+func TestBalanceUpdatedKafkaHandlerProcess(t *testing.T) {
+	t.Run("applies a per-account event to that account only", func(t *testing.T) {
+		balance, _ := entity.NewBalance("account1", 100)
+		balanceMock := &mocks.BalanceGatewayMock{}
+		balanceMock.On("FindById", "account1").Return(balance, nil)
+		balanceMock.On("UpdateBalance", mock.Anything).Return(nil)
+
+		err := newBalanceUpdatedHandler(balanceMock).Process(context.Background(), newBalanceUpdated(map[string]interface{}{
+			"account_id":     "account1",
+			"balance":        90.0,
+			"sequence":       1.0,
+			"transaction_id": "tx1",
+			"created_at":     "2025-03-03T10:00:00Z",
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 90.0, balance.Balance)
+		balanceMock.AssertNumberOfCalls(t, "UpdateBalance", 1)
+	})
+
+	t.Run("counts updates missing before the event", func(t *testing.T) {
+		balance := &entity.AccountBalance{AccountId: "account1", Balance: 100, Sequence: 1}
+		balanceMock := &mocks.BalanceGatewayMock{}
+		balanceMock.On("FindById", "account1").Return(balance, nil)
+		balanceMock.On("UpdateBalance", mock.Anything).Return(nil)
+		before := handler.SequenceGaps.Value()
+
+		err := newBalanceUpdatedHandler(balanceMock).Process(context.Background(), newBalanceUpdated(map[string]interface{}{
+			"account_id": "account1",
+			"balance":    70.0,
+			"sequence":   4.0,
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 70.0, balance.Balance)
+		assert.Equal(t, before+2, handler.SequenceGaps.Value())
+	})
+
+	t.Run("applies both sides of a legacy transfer event", func(t *testing.T) {
+		from, _ := entity.NewBalance("account1", 100)
+		to, _ := entity.NewBalance("account2", 100)
+		balanceMock := &mocks.BalanceGatewayMock{}
+		balanceMock.On("FindById", "account1").Return(from, nil)
+		balanceMock.On("FindById", "account2").Return(to, nil)
+		balanceMock.On("UpdateBalance", mock.Anything).Return(nil)
+
+		err := newBalanceUpdatedHandler(balanceMock).Process(context.Background(), newBalanceUpdated(map[string]interface{}{
+			"account_id_from":         "account1",
+			"account_id_to":           "account2",
+			"balance_account_id_from": 90.0,
+			"balance_account_id_to":   110.0,
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 90.0, from.Balance)
+		assert.Equal(t, 110.0, to.Balance)
+		balanceMock.AssertNumberOfCalls(t, "UpdateBalance", 2)
+	})
+
+	t.Run("rejects events without an account", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+
+		err := newBalanceUpdatedHandler(balanceMock).Process(context.Background(), newBalanceUpdated(map[string]interface{}{
+			"balance": 90.0,
+		}))
+
+		assert.EqualError(t, err, "balance payload carries no account")
+		balanceMock.AssertNotCalled(t, "FindById", mock.Anything)
+	})
+}
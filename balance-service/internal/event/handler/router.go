@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"balance/pkg/events"
+	"balance/pkg/messaging"
+	"context"
+)
+
+// Processor is implemented by handlers that report failures instead of only
+// logging them.
+type Processor interface {
+	Process(ctx context.Context, message events.EventInterface) error
+}
+
+// Router sends each event to the processor registered for its name so the
+// consumer sees the outcome and can retry or dead-letter the message.
+type Router struct {
+	processors map[string]Processor
+}
+
+func NewRouter() *Router {
+	return &Router{
+		processors: make(map[string]Processor),
+	}
+}
+
+func (r *Router) Register(eventName string, processor Processor) {
+	r.processors[eventName] = processor
+}
+
+// Process ignores events nobody registered for.
+func (r *Router) Process(ctx context.Context, message events.EventInterface) error {
+	processor, ok := r.processors[message.GetName()]
+	if !ok {
+		return nil
+	}
+	return processor.Process(ctx, message)
+}
+
+type eventIDKey struct{}
+
+// WithEventID attaches the identity of the delivered event, which the
+// projections record in their inbox to recognize redeliveries.
+func WithEventID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, eventIDKey{}, id)
+}
+
+// EventID returns the identity attached by WithEventID, or "" when the event
+// did not come from a delivery that can repeat.
+func EventID(ctx context.Context) string {
+	id, _ := ctx.Value(eventIDKey{}).(string)
+	return id
+}
+
+// DeliveredEventID identifies the event decoded from msg. A CloudEvent keeps
+// its id when it is published again, so the same event on another offset is
+// still recognized; an event published before CloudEvents gets a new id on
+// every decoding and is identified by the position of msg instead.
+func DeliveredEventID(msg *messaging.Message, decoded events.EventInterface) string {
+	if events.IsCloudEvent(msg.Headers) {
+		return decoded.GetID()
+	}
+	return messaging.MessageID(msg)
+}
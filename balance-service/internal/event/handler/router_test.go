@@ -0,0 +1,62 @@
+package handler_test
+
+import (
+	"balance/internal/event"
+	"balance/internal/event/handler"
+	"balance/pkg/events"
+	"balance/pkg/messaging"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type processorFunc func(ctx context.Context, message events.EventInterface) error
+
+func (f processorFunc) Process(ctx context.Context, message events.EventInterface) error {
+	return f(ctx, message)
+}
+
+func TestRouterProcess(t *testing.T) {
+	t.Run("routes by event name and returns the processor error", func(t *testing.T) {
+		failure := errors.New("boom")
+		var got []string
+		router := handler.NewRouter()
+		router.Register("BalanceUpdated", processorFunc(func(ctx context.Context, message events.EventInterface) error {
+			got = append(got, message.GetName()+"@"+handler.EventID(ctx))
+			return failure
+		}))
+
+		ctx := handler.WithEventID(context.Background(), "balances/0/1")
+		err := router.Process(ctx, event.NewBalanceUpdated(nil))
+
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, []string{"BalanceUpdated@balances/0/1"}, got)
+	})
+
+	t.Run("ignores unregistered events", func(t *testing.T) {
+		router := handler.NewRouter()
+
+		assert.Nil(t, router.Process(context.Background(), event.NewTransactionCreated(nil)))
+	})
+}
+
+func TestDeliveredEventID(t *testing.T) {
+	balanceUpdated := event.NewBalanceUpdated(map[string]interface{}{"account_id": "a1", "balance": 90})
+
+	t.Run("uses the id of a CloudEvent", func(t *testing.T) {
+		value, headers, err := events.NewCloudEventsEncoder("/wallet-service", true, events.JSONCodec{}).Encode(balanceUpdated)
+		require.Nil(t, err)
+		msg := &messaging.Message{Topic: "balances", Offset: 7, Value: value, Headers: headers}
+
+		assert.Equal(t, balanceUpdated.GetID(), handler.DeliveredEventID(msg, balanceUpdated))
+	})
+
+	t.Run("uses the position of an event published before CloudEvents", func(t *testing.T) {
+		msg := &messaging.Message{Topic: "balances", Partition: 2, Offset: 7, Value: []byte(`{}`)}
+
+		assert.Equal(t, "balances/2/7", handler.DeliveredEventID(msg, balanceUpdated))
+	})
+}
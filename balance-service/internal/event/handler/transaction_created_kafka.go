@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"balance/internal/usecase/record_transaction"
+	"balance/pkg/events"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+type TransactionCreatedKafkaHandler struct {
+	RecordTransactionUseCase *record_transaction.RecordTransactionUseCase
+}
+
+func NewTransactionCreatedKafkaHandler(
+	recordTransactionUseCase *record_transaction.RecordTransactionUseCase,
+) *TransactionCreatedKafkaHandler {
+	return &TransactionCreatedKafkaHandler{
+		RecordTransactionUseCase: recordTransactionUseCase,
+	}
+}
+
+func (h *TransactionCreatedKafkaHandler) Handle(ctx context.Context, message events.EventInterface) error {
+	return h.Process(ctx, message)
+}
+
+// Process records the transaction carried by the event and reports failures
+// so the consumer can retry or dead-letter the message.
+func (h *TransactionCreatedKafkaHandler) Process(ctx context.Context, message events.EventInterface) error {
+	if message.GetName() != "TransactionCreated" {
+		return fmt.Errorf("received message with wrong event name %q", message.GetName())
+	}
+
+	// Round-trip the generic payload into the use case input
+	raw, err := json.Marshal(message.GetPayload())
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction payload: %w", err)
+	}
+	var input record_transaction.RecordTransactionInputDTO
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return fmt.Errorf("failed to decode transaction payload: %w", err)
+	}
+
+	input.EventID = EventID(ctx)
+	output, err := h.RecordTransactionUseCase.Execute(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction %s: %w", input.TransactionID, err)
+	}
+	if output.Skipped {
+		log.Printf("Skipped transaction %s: already recorded\n", output.TransactionID)
+		return nil
+	}
+	log.Printf("Recorded transaction %s\n", output.TransactionID)
+	return nil
+}
@@ -0,0 +1,45 @@
+package event
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransactionCreated is created with its payload for a single dispatch and not modified
+// afterwards.
+type TransactionCreated struct {
+	id       string
+	payload  interface{}
+	dateTime time.Time
+}
+
+func NewTransactionCreated(payload interface{}) *TransactionCreated {
+	return ReceivedTransactionCreated(uuid.New().String(), time.Now(), payload)
+}
+
+// ReceivedTransactionCreated rebuilds a consumed event with the id and time it was
+// published with.
+func ReceivedTransactionCreated(id string, dateTime time.Time, payload interface{}) *TransactionCreated {
+	return &TransactionCreated{
+		id:       id,
+		payload:  payload,
+		dateTime: dateTime,
+	}
+}
+
+func (e *TransactionCreated) GetID() string {
+	return e.id
+}
+
+func (e *TransactionCreated) GetName() string {
+	return "TransactionCreated"
+}
+
+func (e *TransactionCreated) GetPayload() interface{} {
+	return e.payload
+}
+
+func (e *TransactionCreated) GetDateTime() time.Time {
+	return e.dateTime
+}
@@ -0,0 +1,9 @@
+package gateway
+
+import "balance/internal/entity"
+
+type AccountTransactionGateway interface {
+	Save(accountTransaction *entity.AccountTransaction) error
+	FindByTransactionId(transactionId string) ([]*entity.AccountTransaction, error)
+	FindByAccountId(accountId string, limit, offset int) ([]*entity.AccountTransaction, error)
+}
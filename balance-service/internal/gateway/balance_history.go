@@ -0,0 +1,13 @@
+package gateway
+
+import (
+	"balance/internal/entity"
+	"time"
+)
+
+type BalanceHistoryGateway interface {
+	Save(change *entity.BalanceChange) error
+	FindByAccountId(accountId string, limit, offset int) ([]*entity.BalanceChange, error)
+	FindLatestAt(accountId string, at time.Time) (*entity.BalanceChange, error)
+	HasSequence(accountId string, sequence int64) (bool, error)
+}
@@ -0,0 +1,8 @@
+package gateway
+
+import "balance/internal/entity"
+
+type InboxGateway interface {
+	Has(consumer, eventId string) (bool, error)
+	Save(processed *entity.ProcessedEvent) error
+}
@@ -3,40 +3,74 @@ package get_account_balance
 import (
 	"balance/internal/gateway"
 	"errors"
+	"time"
 )
 
+// ErrAccountBalanceNotFound is returned for an unknown account, or for an
+// instant before the first recorded change of an account.
+var ErrAccountBalanceNotFound = errors.New("account balance not found")
+
 type GetAccountBalanceInputDTO struct {
-	AccountID string `json:"account_id"`
+	AccountID string     `json:"account_id"`
+	At        *time.Time `json:"at,omitempty"`
 }
 
 type GetAccountBalanceOutputDTO struct {
 	AccountID string  `json:"account_id"`
 	Balance   float64 `json:"balance"`
+	Sequence  int64   `json:"sequence"`
 }
 
 type GetAccountBalanceUseCase struct {
-	BalanceGateway gateway.BalanceGateway
+	BalanceGateway        gateway.BalanceGateway
+	BalanceHistoryGateway gateway.BalanceHistoryGateway
 }
 
-func NewGetAccountBalanceUseCase(balanceGateway gateway.BalanceGateway) *GetAccountBalanceUseCase {
+func NewGetAccountBalanceUseCase(
+	balanceGateway gateway.BalanceGateway,
+	balanceHistoryGateway gateway.BalanceHistoryGateway,
+) *GetAccountBalanceUseCase {
 	return &GetAccountBalanceUseCase{
-		BalanceGateway: balanceGateway,
+		BalanceGateway:        balanceGateway,
+		BalanceHistoryGateway: balanceHistoryGateway,
 	}
 }
 
 func (uc *GetAccountBalanceUseCase) Execute(input GetAccountBalanceInputDTO) (*GetAccountBalanceOutputDTO, error) {
+	if input.At != nil {
+		return uc.balanceAt(input.AccountID, *input.At)
+	}
+
 	// Find the account balance
 	accountBalance, err := uc.BalanceGateway.FindById(input.AccountID)
 	if err != nil {
 		return nil, err
 	}
 	if accountBalance == nil {
-		return nil, errors.New("account balance not found")
+		return nil, ErrAccountBalanceNotFound
 	}
 
 	// Return the account balance
 	return &GetAccountBalanceOutputDTO{
 		AccountID: accountBalance.AccountId,
 		Balance:   accountBalance.Balance,
+		Sequence:  accountBalance.Sequence,
+	}, nil
+}
+
+func (uc *GetAccountBalanceUseCase) balanceAt(accountID string, at time.Time) (*GetAccountBalanceOutputDTO, error) {
+	// The latest change at or before the instant holds the balance as of then
+	change, err := uc.BalanceHistoryGateway.FindLatestAt(accountID, at)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, ErrAccountBalanceNotFound
+	}
+
+	return &GetAccountBalanceOutputDTO{
+		AccountID: change.AccountId,
+		Balance:   change.Balance,
+		Sequence:  change.Sequence,
 	}, nil
 }
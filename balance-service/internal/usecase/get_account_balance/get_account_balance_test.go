@@ -6,18 +6,21 @@ import (
 	"balance/internal/usecase/mocks"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestGetAccountBalanceUseCase_Execute(t *testing.T) {
 	t.Run("should get an existing account balance", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
 		existingBalance, _ := entity.NewBalance("account1", 100.0)
 
 		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
 
-		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock)
+		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock, historyMock)
 
 		input := get_account_balance.GetAccountBalanceInputDTO{
 			AccountID: "account1",
@@ -30,13 +33,15 @@ func TestGetAccountBalanceUseCase_Execute(t *testing.T) {
 		assert.Equal(t, "account1", output.AccountID)
 		assert.Equal(t, 100.0, output.Balance)
 		balanceMock.AssertExpectations(t)
+		historyMock.AssertNotCalled(t, "FindLatestAt", mock.Anything, mock.Anything)
 	})
 
 	t.Run("should return error when account balance not found", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
 		balanceMock.On("FindById", "account1").Return(nil, nil)
 
-		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock)
+		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock, historyMock)
 
 		input := get_account_balance.GetAccountBalanceInputDTO{
 			AccountID: "account1",
@@ -52,9 +57,10 @@ func TestGetAccountBalanceUseCase_Execute(t *testing.T) {
 
 	t.Run("should return error when gateway returns error", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
 		balanceMock.On("FindById", "account1").Return(nil, errors.New("database error"))
 
-		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock)
+		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock, historyMock)
 
 		input := get_account_balance.GetAccountBalanceInputDTO{
 			AccountID: "account1",
@@ -67,4 +73,49 @@ func TestGetAccountBalanceUseCase_Execute(t *testing.T) {
 		assert.Equal(t, "database error", err.Error())
 		balanceMock.AssertExpectations(t)
 	})
+
+	t.Run("should get the balance as of a point in time", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		at := time.Date(2025, 3, 3, 23, 59, 59, 0, time.UTC)
+		change, _ := entity.NewBalanceChange("account1", 75.0, 4, "tx4", at.Add(-time.Hour))
+
+		historyMock.On("FindLatestAt", "account1", at).Return(change, nil)
+
+		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock, historyMock)
+
+		input := get_account_balance.GetAccountBalanceInputDTO{
+			AccountID: "account1",
+			At:        &at,
+		}
+
+		output, err := useCase.Execute(input)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "account1", output.AccountID)
+		assert.Equal(t, 75.0, output.Balance)
+		assert.Equal(t, int64(4), output.Sequence)
+		historyMock.AssertExpectations(t)
+		balanceMock.AssertNotCalled(t, "FindById", mock.Anything)
+	})
+
+	t.Run("should return error when there is no balance at that point in time", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		at := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+		historyMock.On("FindLatestAt", "account1", at).Return(nil, nil)
+
+		useCase := get_account_balance.NewGetAccountBalanceUseCase(balanceMock, historyMock)
+
+		input := get_account_balance.GetAccountBalanceInputDTO{
+			AccountID: "account1",
+			At:        &at,
+		}
+
+		output, err := useCase.Execute(input)
+
+		assert.Nil(t, output)
+		assert.ErrorIs(t, err, get_account_balance.ErrAccountBalanceNotFound)
+	})
 }
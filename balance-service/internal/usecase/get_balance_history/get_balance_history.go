@@ -0,0 +1,86 @@
+package get_balance_history
+
+import (
+	"balance/internal/gateway"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrBalanceHistoryNotFound is returned when an account has no recorded
+// balance changes at all.
+var ErrBalanceHistoryNotFound = errors.New("balance history not found")
+
+type GetBalanceHistoryInputDTO struct {
+	AccountID string `json:"account_id"`
+	Limit     int    `json:"limit"`
+	Offset    int    `json:"offset"`
+}
+
+type BalanceChangeOutputDTO struct {
+	Balance       float64   `json:"balance"`
+	Sequence      int64     `json:"sequence"`
+	TransactionID string    `json:"transaction_id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+type GetBalanceHistoryOutputDTO struct {
+	AccountID string                   `json:"account_id"`
+	Limit     int                      `json:"limit"`
+	Offset    int                      `json:"offset"`
+	Changes   []BalanceChangeOutputDTO `json:"changes"`
+}
+
+type GetBalanceHistoryUseCase struct {
+	BalanceHistoryGateway gateway.BalanceHistoryGateway
+}
+
+func NewGetBalanceHistoryUseCase(balanceHistoryGateway gateway.BalanceHistoryGateway) *GetBalanceHistoryUseCase {
+	return &GetBalanceHistoryUseCase{
+		BalanceHistoryGateway: balanceHistoryGateway,
+	}
+}
+
+func (uc *GetBalanceHistoryUseCase) Execute(input GetBalanceHistoryInputDTO) (*GetBalanceHistoryOutputDTO, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	offset := input.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	changes, err := uc.BalanceHistoryGateway.FindByAccountId(input.AccountID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	// Past the last page is an empty page, but no first page is no history
+	if len(changes) == 0 && offset == 0 {
+		return nil, ErrBalanceHistoryNotFound
+	}
+
+	output := &GetBalanceHistoryOutputDTO{
+		AccountID: input.AccountID,
+		Limit:     limit,
+		Offset:    offset,
+		Changes:   make([]BalanceChangeOutputDTO, 0, len(changes)),
+	}
+	for _, change := range changes {
+		output.Changes = append(output.Changes, BalanceChangeOutputDTO{
+			Balance:       change.Balance,
+			Sequence:      change.Sequence,
+			TransactionID: change.TransactionId,
+			OccurredAt:    change.OccurredAt,
+		})
+	}
+
+	return output, nil
+}
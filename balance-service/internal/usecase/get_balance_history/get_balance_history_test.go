@@ -0,0 +1,92 @@
+package get_balance_history_test
+
+import (
+	"balance/internal/entity"
+	"balance/internal/usecase/get_balance_history"
+	"balance/internal/usecase/mocks"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBalanceHistoryUseCase_Execute(t *testing.T) {
+	t.Run("should return the account balance history page", func(t *testing.T) {
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		occurredAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+		change2, _ := entity.NewBalanceChange("account1", 80.0, 2, "tx2", occurredAt.Add(time.Hour))
+		change1, _ := entity.NewBalanceChange("account1", 90.0, 1, "tx1", occurredAt)
+
+		historyMock.On("FindByAccountId", "account1", 10, 20).Return([]*entity.BalanceChange{change2, change1}, nil)
+
+		useCase := get_balance_history.NewGetBalanceHistoryUseCase(historyMock)
+
+		input := get_balance_history.GetBalanceHistoryInputDTO{
+			AccountID: "account1",
+			Limit:     10,
+			Offset:    20,
+		}
+
+		output, err := useCase.Execute(input)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "account1", output.AccountID)
+		assert.Equal(t, 10, output.Limit)
+		assert.Equal(t, 20, output.Offset)
+		assert.Len(t, output.Changes, 2)
+		assert.Equal(t, 80.0, output.Changes[0].Balance)
+		assert.Equal(t, "tx2", output.Changes[0].TransactionID)
+		assert.Equal(t, int64(1), output.Changes[1].Sequence)
+		historyMock.AssertExpectations(t)
+	})
+
+	t.Run("should apply default and maximum page sizes", func(t *testing.T) {
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		change, _ := entity.NewBalanceChange("account1", 90.0, 1, "tx1", time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC))
+		historyMock.On("FindByAccountId", "account1", get_balance_history.DefaultLimit, 0).Return([]*entity.BalanceChange{change}, nil)
+		historyMock.On("FindByAccountId", "account1", get_balance_history.MaxLimit, 0).Return([]*entity.BalanceChange{change}, nil)
+
+		useCase := get_balance_history.NewGetBalanceHistoryUseCase(historyMock)
+
+		output, err := useCase.Execute(get_balance_history.GetBalanceHistoryInputDTO{AccountID: "account1", Offset: -5})
+		assert.Nil(t, err)
+		assert.Equal(t, get_balance_history.DefaultLimit, output.Limit)
+		assert.Equal(t, 0, output.Offset)
+		assert.NotNil(t, output.Changes)
+
+		output, err = useCase.Execute(get_balance_history.GetBalanceHistoryInputDTO{AccountID: "account1", Limit: 1000})
+		assert.Nil(t, err)
+		assert.Equal(t, get_balance_history.MaxLimit, output.Limit)
+		historyMock.AssertExpectations(t)
+	})
+
+	t.Run("should return not found when the account has no history", func(t *testing.T) {
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("FindByAccountId", "account1", get_balance_history.DefaultLimit, 0).Return([]*entity.BalanceChange{}, nil)
+		historyMock.On("FindByAccountId", "account1", get_balance_history.DefaultLimit, 20).Return([]*entity.BalanceChange{}, nil)
+
+		useCase := get_balance_history.NewGetBalanceHistoryUseCase(historyMock)
+
+		output, err := useCase.Execute(get_balance_history.GetBalanceHistoryInputDTO{AccountID: "account1"})
+		assert.Nil(t, output)
+		assert.ErrorIs(t, err, get_balance_history.ErrBalanceHistoryNotFound)
+
+		// A page past the end is empty rather than missing
+		output, err = useCase.Execute(get_balance_history.GetBalanceHistoryInputDTO{AccountID: "account1", Offset: 20})
+		assert.Nil(t, err)
+		assert.Empty(t, output.Changes)
+	})
+
+	t.Run("should return error when gateway returns error", func(t *testing.T) {
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("FindByAccountId", "account1", get_balance_history.DefaultLimit, 0).Return(nil, errors.New("database error"))
+
+		useCase := get_balance_history.NewGetBalanceHistoryUseCase(historyMock)
+
+		output, err := useCase.Execute(get_balance_history.GetBalanceHistoryInputDTO{AccountID: "account1"})
+
+		assert.Nil(t, output)
+		assert.Equal(t, "database error", err.Error())
+	})
+}
@@ -0,0 +1,79 @@
+package list_account_transactions
+
+import (
+	"balance/internal/gateway"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type ListAccountTransactionsInputDTO struct {
+	AccountID string `json:"account_id"`
+	Limit     int    `json:"limit"`
+	Offset    int    `json:"offset"`
+}
+
+type AccountTransactionOutputDTO struct {
+	TransactionID         string    `json:"transaction_id"`
+	CounterpartyAccountID string    `json:"counterparty_account_id"`
+	Direction             string    `json:"direction"`
+	Amount                float64   `json:"amount"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+type ListAccountTransactionsOutputDTO struct {
+	AccountID    string                        `json:"account_id"`
+	Limit        int                           `json:"limit"`
+	Offset       int                           `json:"offset"`
+	Transactions []AccountTransactionOutputDTO `json:"transactions"`
+}
+
+type ListAccountTransactionsUseCase struct {
+	AccountTransactionGateway gateway.AccountTransactionGateway
+}
+
+func NewListAccountTransactionsUseCase(accountTransactionGateway gateway.AccountTransactionGateway) *ListAccountTransactionsUseCase {
+	return &ListAccountTransactionsUseCase{
+		AccountTransactionGateway: accountTransactionGateway,
+	}
+}
+
+func (uc *ListAccountTransactionsUseCase) Execute(input ListAccountTransactionsInputDTO) (*ListAccountTransactionsOutputDTO, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	offset := input.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	accountTransactions, err := uc.AccountTransactionGateway.FindByAccountId(input.AccountID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ListAccountTransactionsOutputDTO{
+		AccountID:    input.AccountID,
+		Limit:        limit,
+		Offset:       offset,
+		Transactions: make([]AccountTransactionOutputDTO, 0, len(accountTransactions)),
+	}
+	for _, accountTransaction := range accountTransactions {
+		output.Transactions = append(output.Transactions, AccountTransactionOutputDTO{
+			TransactionID:         accountTransaction.TransactionId,
+			CounterpartyAccountID: accountTransaction.CounterpartyAccountId,
+			Direction:             accountTransaction.Direction,
+			Amount:                accountTransaction.Amount,
+			CreatedAt:             accountTransaction.CreatedAt,
+		})
+	}
+
+	return output, nil
+}
@@ -0,0 +1,69 @@
+package list_account_transactions_test
+
+import (
+	"balance/internal/entity"
+	"balance/internal/usecase/list_account_transactions"
+	"balance/internal/usecase/mocks"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAccountTransactionsUseCase_Execute(t *testing.T) {
+	t.Run("should list the account transactions page", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		createdAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+		credit, _ := entity.NewAccountTransaction("tx2", "account1", "account2", entity.DirectionCredit, 5.0, createdAt.Add(time.Hour))
+		debit, _ := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 10.0, createdAt)
+
+		transactionMock.On("FindByAccountId", "account1", 10, 0).Return([]*entity.AccountTransaction{credit, debit}, nil)
+
+		useCase := list_account_transactions.NewListAccountTransactionsUseCase(transactionMock)
+
+		output, err := useCase.Execute(list_account_transactions.ListAccountTransactionsInputDTO{
+			AccountID: "account1",
+			Limit:     10,
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "account1", output.AccountID)
+		assert.Len(t, output.Transactions, 2)
+		assert.Equal(t, "tx2", output.Transactions[0].TransactionID)
+		assert.Equal(t, "account2", output.Transactions[0].CounterpartyAccountID)
+		assert.Equal(t, entity.DirectionCredit, output.Transactions[0].Direction)
+		assert.Equal(t, 10.0, output.Transactions[1].Amount)
+		transactionMock.AssertExpectations(t)
+	})
+
+	t.Run("should apply default and maximum page sizes", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		transactionMock.On("FindByAccountId", "account1", list_account_transactions.DefaultLimit, 0).Return([]*entity.AccountTransaction{}, nil)
+		transactionMock.On("FindByAccountId", "account1", list_account_transactions.MaxLimit, 0).Return([]*entity.AccountTransaction{}, nil)
+
+		useCase := list_account_transactions.NewListAccountTransactionsUseCase(transactionMock)
+
+		output, err := useCase.Execute(list_account_transactions.ListAccountTransactionsInputDTO{AccountID: "account1", Offset: -1})
+		assert.Nil(t, err)
+		assert.Equal(t, list_account_transactions.DefaultLimit, output.Limit)
+		assert.NotNil(t, output.Transactions)
+
+		output, err = useCase.Execute(list_account_transactions.ListAccountTransactionsInputDTO{AccountID: "account1", Limit: 500})
+		assert.Nil(t, err)
+		assert.Equal(t, list_account_transactions.MaxLimit, output.Limit)
+		transactionMock.AssertExpectations(t)
+	})
+
+	t.Run("should return error when gateway returns error", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		transactionMock.On("FindByAccountId", "account1", list_account_transactions.DefaultLimit, 0).Return(nil, errors.New("database error"))
+
+		useCase := list_account_transactions.NewListAccountTransactionsUseCase(transactionMock)
+
+		output, err := useCase.Execute(list_account_transactions.ListAccountTransactionsInputDTO{AccountID: "account1"})
+
+		assert.Nil(t, output)
+		assert.Equal(t, "database error", err.Error())
+	})
+}
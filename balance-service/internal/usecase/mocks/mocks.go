@@ -2,6 +2,9 @@ package mocks
 
 import (
 	"balance/internal/entity"
+	"balance/pkg/uow"
+	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -27,3 +30,98 @@ func (m *BalanceGatewayMock) UpdateBalance(account *entity.AccountBalance) error
 	args := m.Called(account)
 	return args.Error(0)
 }
+
+type BalanceHistoryGatewayMock struct {
+	mock.Mock
+}
+
+func (m *BalanceHistoryGatewayMock) Save(change *entity.BalanceChange) error {
+	args := m.Called(change)
+	return args.Error(0)
+}
+
+func (m *BalanceHistoryGatewayMock) FindByAccountId(accountId string, limit, offset int) ([]*entity.BalanceChange, error) {
+	args := m.Called(accountId, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.BalanceChange), args.Error(1)
+}
+
+func (m *BalanceHistoryGatewayMock) FindLatestAt(accountId string, at time.Time) (*entity.BalanceChange, error) {
+	args := m.Called(accountId, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.BalanceChange), args.Error(1)
+}
+
+func (m *BalanceHistoryGatewayMock) HasSequence(accountId string, sequence int64) (bool, error) {
+	args := m.Called(accountId, sequence)
+	return args.Bool(0), args.Error(1)
+}
+
+type AccountTransactionGatewayMock struct {
+	mock.Mock
+}
+
+func (m *AccountTransactionGatewayMock) Save(accountTransaction *entity.AccountTransaction) error {
+	args := m.Called(accountTransaction)
+	return args.Error(0)
+}
+
+func (m *AccountTransactionGatewayMock) FindByTransactionId(transactionId string) ([]*entity.AccountTransaction, error) {
+	args := m.Called(transactionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.AccountTransaction), args.Error(1)
+}
+
+func (m *AccountTransactionGatewayMock) FindByAccountId(accountId string, limit, offset int) ([]*entity.AccountTransaction, error) {
+	args := m.Called(accountId, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.AccountTransaction), args.Error(1)
+}
+
+type InboxGatewayMock struct {
+	mock.Mock
+}
+
+func (m *InboxGatewayMock) Has(consumer, eventId string) (bool, error) {
+	args := m.Called(consumer, eventId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *InboxGatewayMock) Save(processed *entity.ProcessedEvent) error {
+	args := m.Called(processed)
+	return args.Error(0)
+}
+
+type UowMock struct {
+	mock.Mock
+}
+
+func (m *UowMock) Register(name string, fc uow.RepositoryFactory) {
+	m.Called(name, fc)
+}
+
+func (m *UowMock) GetRepository(ctx context.Context, name string) (interface{}, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0), args.Error(1)
+}
+
+func (m *UowMock) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx, fn)
+	// Run the function so the repositories it asks for are exercised
+	if args.Get(0) == nil {
+		return fn(ctx)
+	}
+	return args.Error(0)
+}
+
+func (m *UowMock) UnRegister(name string) {
+	m.Called(name)
+}
@@ -0,0 +1,114 @@
+package record_transaction
+
+import (
+	"balance/internal/entity"
+	"balance/internal/gateway"
+	"balance/pkg/uow"
+	"context"
+	"time"
+)
+
+type RecordTransactionInputDTO struct {
+	TransactionID string    `json:"id"`
+	AccountIDFrom string    `json:"account_id_from"`
+	AccountIDTo   string    `json:"account_id_to"`
+	Amount        float64   `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+	// EventID identifies the delivered event; when set, redeliveries are
+	// recognized through the inbox and skipped.
+	EventID string `json:"-"`
+}
+
+type RecordTransactionOutputDTO struct {
+	TransactionID string `json:"transaction_id"`
+	Skipped       bool   `json:"skipped"`
+}
+
+// InboxConsumer names this projection in the processed-events inbox.
+const InboxConsumer = "account_transactions"
+
+type RecordTransactionUseCase struct {
+	Uow uow.UowInterface
+}
+
+func NewRecordTransactionUseCase(uow uow.UowInterface) *RecordTransactionUseCase {
+	return &RecordTransactionUseCase{
+		Uow: uow,
+	}
+}
+
+func (uc *RecordTransactionUseCase) Execute(ctx context.Context, input RecordTransactionInputDTO) (*RecordTransactionOutputDTO, error) {
+	output := &RecordTransactionOutputDTO{TransactionID: input.TransactionID}
+
+	err := uc.Uow.Do(ctx, func(ctx context.Context) error {
+		// Get repositories
+		accountTransactionGateway, err := uow.Get[gateway.AccountTransactionGateway](ctx, uc.Uow)
+		if err != nil {
+			return err
+		}
+		inboxGateway, err := uow.Get[gateway.InboxGateway](ctx, uc.Uow)
+		if err != nil {
+			return err
+		}
+
+		// A redelivered event was already applied in an earlier transaction
+		if input.EventID != "" {
+			processed, err := inboxGateway.Has(InboxConsumer, input.EventID)
+			if err != nil {
+				return err
+			}
+			if processed {
+				output.Skipped = true
+				return nil
+			}
+		}
+
+		// The same transaction published twice is already in the read model
+		existing, err := accountTransactionGateway.FindByTransactionId(input.TransactionID)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			output.Skipped = true
+			return uc.markProcessed(inboxGateway, input.EventID)
+		}
+
+		// Each account sees its own side of the transfer
+		debit, err := entity.NewAccountTransaction(
+			input.TransactionID, input.AccountIDFrom, input.AccountIDTo, entity.DirectionDebit, input.Amount, input.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		credit, err := entity.NewAccountTransaction(
+			input.TransactionID, input.AccountIDTo, input.AccountIDFrom, entity.DirectionCredit, input.Amount, input.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, accountTransaction := range []*entity.AccountTransaction{debit, credit} {
+			if err := accountTransactionGateway.Save(accountTransaction); err != nil {
+				return err
+			}
+		}
+
+		return uc.markProcessed(inboxGateway, input.EventID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func (uc *RecordTransactionUseCase) markProcessed(inboxGateway gateway.InboxGateway, eventId string) error {
+	if eventId == "" {
+		return nil
+	}
+	processed, err := entity.NewProcessedEvent(InboxConsumer, eventId)
+	if err != nil {
+		return err
+	}
+	return inboxGateway.Save(processed)
+}
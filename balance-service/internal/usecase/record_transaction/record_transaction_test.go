@@ -0,0 +1,136 @@
+package record_transaction_test
+
+import (
+	"balance/internal/entity"
+	"balance/internal/gateway"
+	"balance/internal/usecase/mocks"
+	"balance/internal/usecase/record_transaction"
+	"balance/pkg/uow"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newUowMock(transactionMock *mocks.AccountTransactionGatewayMock, inboxMock *mocks.InboxGatewayMock) *mocks.UowMock {
+	uowMock := &mocks.UowMock{}
+	uowMock.On("Do", mock.Anything, mock.Anything).Return(nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.AccountTransactionGateway]()).Return(transactionMock, nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.InboxGateway]()).Return(inboxMock, nil)
+	return uowMock
+}
+
+func TestRecordTransactionUseCase_Execute(t *testing.T) {
+	createdAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	input := record_transaction.RecordTransactionInputDTO{
+		TransactionID: "tx1",
+		AccountIDFrom: "account1",
+		AccountIDTo:   "account2",
+		Amount:        10.0,
+		CreatedAt:     createdAt,
+	}
+
+	t.Run("should record one entry per account", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		transactionMock.On("FindByTransactionId", "tx1").Return([]*entity.AccountTransaction{}, nil)
+		transactionMock.On("Save", mock.MatchedBy(func(at *entity.AccountTransaction) bool {
+			return at.AccountId == "account1" && at.CounterpartyAccountId == "account2" &&
+				at.Direction == entity.DirectionDebit && at.Amount == 10.0 && at.CreatedAt.Equal(createdAt)
+		})).Return(nil).Once()
+		transactionMock.On("Save", mock.MatchedBy(func(at *entity.AccountTransaction) bool {
+			return at.AccountId == "account2" && at.CounterpartyAccountId == "account1" &&
+				at.Direction == entity.DirectionCredit && at.Amount == 10.0
+		})).Return(nil).Once()
+
+		useCase := record_transaction.NewRecordTransactionUseCase(newUowMock(transactionMock, &mocks.InboxGatewayMock{}))
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "tx1", output.TransactionID)
+		assert.False(t, output.Skipped)
+		transactionMock.AssertExpectations(t)
+	})
+
+	t.Run("should skip transactions already recorded", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		existing, _ := entity.NewAccountTransaction("tx1", "account1", "account2", entity.DirectionDebit, 10.0, createdAt)
+		transactionMock.On("FindByTransactionId", "tx1").Return([]*entity.AccountTransaction{existing}, nil)
+
+		useCase := record_transaction.NewRecordTransactionUseCase(newUowMock(transactionMock, &mocks.InboxGatewayMock{}))
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.True(t, output.Skipped)
+		transactionMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("should return error when transaction is invalid", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		transactionMock.On("FindByTransactionId", "tx1").Return([]*entity.AccountTransaction{}, nil)
+
+		useCase := record_transaction.NewRecordTransactionUseCase(newUowMock(transactionMock, &mocks.InboxGatewayMock{}))
+
+		invalid := input
+		invalid.Amount = 0
+		output, err := useCase.Execute(context.Background(), invalid)
+
+		assert.Nil(t, output)
+		assert.Equal(t, entity.ErrInvalidAmount, err.Error())
+		transactionMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("should return error when save fails", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		transactionMock.On("FindByTransactionId", "tx1").Return([]*entity.AccountTransaction{}, nil)
+		transactionMock.On("Save", mock.Anything).Return(errors.New("database error"))
+
+		useCase := record_transaction.NewRecordTransactionUseCase(newUowMock(transactionMock, &mocks.InboxGatewayMock{}))
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, output)
+		assert.Equal(t, "database error", err.Error())
+	})
+	t.Run("should mark the event processed after recording", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		transactionMock.On("FindByTransactionId", "tx1").Return([]*entity.AccountTransaction{}, nil)
+		transactionMock.On("Save", mock.Anything).Return(nil)
+		inboxMock := &mocks.InboxGatewayMock{}
+		inboxMock.On("Has", "account_transactions", "transactions/1/3").Return(false, nil)
+		inboxMock.On("Save", mock.MatchedBy(func(processed *entity.ProcessedEvent) bool {
+			return processed.Consumer == "account_transactions" && processed.EventId == "transactions/1/3"
+		})).Return(nil)
+
+		useCase := record_transaction.NewRecordTransactionUseCase(newUowMock(transactionMock, inboxMock))
+
+		withEvent := input
+		withEvent.EventID = "transactions/1/3"
+		output, err := useCase.Execute(context.Background(), withEvent)
+
+		assert.Nil(t, err)
+		assert.False(t, output.Skipped)
+		inboxMock.AssertExpectations(t)
+	})
+
+	t.Run("should skip events already in the inbox", func(t *testing.T) {
+		transactionMock := &mocks.AccountTransactionGatewayMock{}
+		inboxMock := &mocks.InboxGatewayMock{}
+		inboxMock.On("Has", "account_transactions", "transactions/1/3").Return(true, nil)
+
+		useCase := record_transaction.NewRecordTransactionUseCase(newUowMock(transactionMock, inboxMock))
+
+		withEvent := input
+		withEvent.EventID = "transactions/1/3"
+		output, err := useCase.Execute(context.Background(), withEvent)
+
+		assert.Nil(t, err)
+		assert.True(t, output.Skipped)
+		transactionMock.AssertNotCalled(t, "FindByTransactionId", mock.Anything)
+		transactionMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
@@ -1,55 +1,164 @@
 package update_account_balance
 
 import (
+	"balance/internal/entity"
 	"balance/internal/gateway"
+	"balance/pkg/uow"
+	"context"
 	"errors"
+	"time"
 )
 
 type UpdateAccountBalanceInputDTO struct {
-	AccountID string  `json:"account_id"`
-	Balance   float64 `json:"balance"`
+	AccountID     string    `json:"account_id"`
+	Balance       float64   `json:"balance"`
+	Sequence      int64     `json:"sequence"`
+	TransactionID string    `json:"transaction_id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	// EventID identifies the delivered event; when set, redeliveries are
+	// recognized through the inbox and skipped.
+	EventID string `json:"-"`
 }
 
 type UpdateAccountBalanceOutputDTO struct {
 	AccountID string  `json:"account_id"`
 	Balance   float64 `json:"balance"`
+	Sequence  int64   `json:"sequence"`
+	Skipped   bool    `json:"skipped"`
+	Gap       int64   `json:"gap"`
 }
 
+// InboxConsumer names this projection in the processed-events inbox.
+const InboxConsumer = "account_balances"
+
 type UpdateAccountBalanceUseCase struct {
-	BalanceGateway gateway.BalanceGateway
+	Uow uow.UowInterface
 }
 
-func NewUpdateAccountBalanceUseCase(balanceGateway gateway.BalanceGateway) *UpdateAccountBalanceUseCase {
+func NewUpdateAccountBalanceUseCase(uow uow.UowInterface) *UpdateAccountBalanceUseCase {
 	return &UpdateAccountBalanceUseCase{
-		BalanceGateway: balanceGateway,
+		Uow: uow,
 	}
 }
 
-func (uc *UpdateAccountBalanceUseCase) Execute(input UpdateAccountBalanceInputDTO) (*UpdateAccountBalanceOutputDTO, error) {
-	// Find the existing account balance
-	existingBalance, err := uc.BalanceGateway.FindById(input.AccountID)
+func (uc *UpdateAccountBalanceUseCase) Execute(ctx context.Context, input UpdateAccountBalanceInputDTO) (*UpdateAccountBalanceOutputDTO, error) {
+	var output *UpdateAccountBalanceOutputDTO
+
+	err := uc.Uow.Do(ctx, func(ctx context.Context) error {
+		// Get repositories
+		balanceGateway, err := uow.Get[gateway.BalanceGateway](ctx, uc.Uow)
+		if err != nil {
+			return err
+		}
+		balanceHistoryGateway, err := uow.Get[gateway.BalanceHistoryGateway](ctx, uc.Uow)
+		if err != nil {
+			return err
+		}
+		inboxGateway, err := uow.Get[gateway.InboxGateway](ctx, uc.Uow)
+		if err != nil {
+			return err
+		}
+
+		// A redelivered event was already applied in an earlier transaction
+		eventId := ""
+		if input.EventID != "" {
+			eventId = input.EventID + ":" + input.AccountID
+			processed, err := inboxGateway.Has(InboxConsumer, eventId)
+			if err != nil {
+				return err
+			}
+			if processed {
+				output = &UpdateAccountBalanceOutputDTO{AccountID: input.AccountID, Skipped: true}
+				return nil
+			}
+		}
+
+		// Find the existing account balance
+		existingBalance, err := balanceGateway.FindById(input.AccountID)
+		if err != nil {
+			return err
+		}
+		if existingBalance == nil {
+			return errors.New("account balance not found")
+		}
+
+		// Duplicated or out-of-order updates leave the current balance alone,
+		// but an update that arrived late still belongs in the history
+		if existingBalance.IsStale(input.Sequence) {
+			recorded, err := balanceHistoryGateway.HasSequence(input.AccountID, input.Sequence)
+			if err != nil {
+				return err
+			}
+			if !recorded {
+				if err := uc.recordChange(balanceHistoryGateway, input.Balance, input); err != nil {
+					return err
+				}
+			}
+			output = &UpdateAccountBalanceOutputDTO{
+				AccountID: existingBalance.AccountId,
+				Balance:   existingBalance.Balance,
+				Sequence:  existingBalance.Sequence,
+				Skipped:   true,
+			}
+			return uc.markProcessed(inboxGateway, eventId)
+		}
+		gap := existingBalance.Gap(input.Sequence)
+
+		// Update the balance
+		err = existingBalance.ApplyUpdate(input.Balance, input.Sequence)
+		if err != nil {
+			return err
+		}
+
+		// Save the updated account balance
+		err = balanceGateway.UpdateBalance(existingBalance)
+		if err != nil {
+			return err
+		}
+
+		// Record the change so the balance can be queried at any point in time
+		err = uc.recordChange(balanceHistoryGateway, existingBalance.Balance, input)
+		if err != nil {
+			return err
+		}
+
+		output = &UpdateAccountBalanceOutputDTO{
+			AccountID: existingBalance.AccountId,
+			Balance:   existingBalance.Balance,
+			Sequence:  existingBalance.Sequence,
+			Gap:       gap,
+		}
+		return uc.markProcessed(inboxGateway, eventId)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if existingBalance == nil {
-		return nil, errors.New("account balance not found")
-	}
 
-	// Update the balance
-	err = existingBalance.UpdateBalance(input.Balance)
+	// Return the updated account balance
+	return output, nil
+}
+
+func (uc *UpdateAccountBalanceUseCase) recordChange(balanceHistoryGateway gateway.BalanceHistoryGateway, balance float64, input UpdateAccountBalanceInputDTO) error {
+	change, err := entity.NewBalanceChange(
+		input.AccountID,
+		balance,
+		input.Sequence,
+		input.TransactionID,
+		input.OccurredAt,
+	)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return balanceHistoryGateway.Save(change)
+}
 
-	// Save the updated account balance
-	err = uc.BalanceGateway.UpdateBalance(existingBalance)
+func (uc *UpdateAccountBalanceUseCase) markProcessed(inboxGateway gateway.InboxGateway, eventId string) error {
+	if eventId == "" {
+		return nil
+	}
+	processed, err := entity.NewProcessedEvent(InboxConsumer, eventId)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// Return the updated account balance
-	return &UpdateAccountBalanceOutputDTO{
-		AccountID: existingBalance.AccountId,
-		Balance:   existingBalance.Balance,
-	}, nil
+	return inboxGateway.Save(processed)
 }
@@ -1,19 +1,43 @@
 package update_account_balance_test
 
 import (
+	"balance/internal/database"
 	"balance/internal/entity"
+	"balance/internal/gateway"
 	"balance/internal/usecase/mocks"
 	"balance/internal/usecase/update_account_balance"
+	"balance/pkg/dialect"
+	"balance/pkg/uow"
+	"context"
+	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
 )
 
+func newUowMock(
+	balanceMock *mocks.BalanceGatewayMock,
+	historyMock *mocks.BalanceHistoryGatewayMock,
+	inboxMock *mocks.InboxGatewayMock,
+) *mocks.UowMock {
+	uowMock := &mocks.UowMock{}
+	uowMock.On("Do", mock.Anything, mock.Anything).Return(nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.BalanceGateway]()).Return(balanceMock, nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.BalanceHistoryGateway]()).Return(historyMock, nil)
+	uowMock.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.InboxGateway]()).Return(inboxMock, nil)
+	return uowMock
+}
+
 func TestUpdateAccountBalanceUseCase_Execute(t *testing.T) {
 	t.Run("should update an existing account balance", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("Save", mock.Anything).Return(nil)
 		existingBalance, _ := entity.NewBalance("account1", 100.0)
 
 		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
@@ -21,14 +45,14 @@ func TestUpdateAccountBalanceUseCase_Execute(t *testing.T) {
 			return acc.AccountId == "account1" && acc.Balance == 200.0
 		})).Return(nil)
 
-		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(balanceMock)
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
 
 		input := update_account_balance.UpdateAccountBalanceInputDTO{
 			AccountID: "account1",
 			Balance:   200.0,
 		}
 
-		output, err := useCase.Execute(input)
+		output, err := useCase.Execute(context.Background(), input)
 
 		assert.Nil(t, err)
 		assert.NotNil(t, output)
@@ -39,16 +63,18 @@ func TestUpdateAccountBalanceUseCase_Execute(t *testing.T) {
 
 	t.Run("should return error when account balance not found", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("Save", mock.Anything).Return(nil)
 		balanceMock.On("FindById", "account1").Return(nil, errors.New("not found"))
 
-		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(balanceMock)
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
 
 		input := update_account_balance.UpdateAccountBalanceInputDTO{
 			AccountID: "account1",
 			Balance:   200.0,
 		}
 
-		output, err := useCase.Execute(input)
+		output, err := useCase.Execute(context.Background(), input)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, output)
@@ -58,16 +84,18 @@ func TestUpdateAccountBalanceUseCase_Execute(t *testing.T) {
 
 	t.Run("should return error when account balance is nil", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("Save", mock.Anything).Return(nil)
 		balanceMock.On("FindById", "account1").Return(nil, nil)
 
-		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(balanceMock)
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
 
 		input := update_account_balance.UpdateAccountBalanceInputDTO{
 			AccountID: "account1",
 			Balance:   200.0,
 		}
 
-		output, err := useCase.Execute(input)
+		output, err := useCase.Execute(context.Background(), input)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, output)
@@ -77,18 +105,20 @@ func TestUpdateAccountBalanceUseCase_Execute(t *testing.T) {
 
 	t.Run("should return error when balance is negative", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("Save", mock.Anything).Return(nil)
 		existingBalance, _ := entity.NewBalance("account1", 100.0)
 
 		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
 
-		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(balanceMock)
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
 
 		input := update_account_balance.UpdateAccountBalanceInputDTO{
 			AccountID: "account1",
 			Balance:   -50.0,
 		}
 
-		output, err := useCase.Execute(input)
+		output, err := useCase.Execute(context.Background(), input)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, output)
@@ -98,22 +128,300 @@ func TestUpdateAccountBalanceUseCase_Execute(t *testing.T) {
 
 	t.Run("should return error when update balance operation fails", func(t *testing.T) {
 		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("Save", mock.Anything).Return(nil)
 		existingBalance, _ := entity.NewBalance("account1", 100.0)
 
 		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
 		balanceMock.On("UpdateBalance", mock.Anything).Return(errors.New("database error"))
 
-		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(balanceMock)
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
 
 		input := update_account_balance.UpdateAccountBalanceInputDTO{
 			AccountID: "account1",
 			Balance:   200.0,
 		}
 
-		output, err := useCase.Execute(input)
+		output, err := useCase.Execute(context.Background(), input)
 
 		assert.NotNil(t, err)
 		assert.Nil(t, output)
 		assert.Equal(t, "database error", err.Error())
 	})
+
+	t.Run("should skip stale and duplicate updates", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("HasSequence", "account1", mock.Anything).Return(true, nil)
+		existingBalance, _ := entity.NewBalance("account1", 100.0)
+		existingBalance.Sequence = 5
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
+
+		for _, sequence := range []int64{4, 5} {
+			input := update_account_balance.UpdateAccountBalanceInputDTO{
+				AccountID: "account1",
+				Balance:   50.0,
+				Sequence:  sequence,
+			}
+
+			output, err := useCase.Execute(context.Background(), input)
+
+			assert.Nil(t, err)
+			assert.True(t, output.Skipped)
+			assert.Equal(t, 100.0, output.Balance)
+			assert.Equal(t, int64(5), output.Sequence)
+		}
+		balanceMock.AssertNotCalled(t, "UpdateBalance")
+	})
+
+	t.Run("should apply update and report sequence gap", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("Save", mock.Anything).Return(nil)
+		existingBalance, _ := entity.NewBalance("account1", 100.0)
+		existingBalance.Sequence = 5
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+		balanceMock.On("UpdateBalance", mock.MatchedBy(func(acc *entity.AccountBalance) bool {
+			return acc.Balance == 80.0 && acc.Sequence == 8
+		})).Return(nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID: "account1",
+			Balance:   80.0,
+			Sequence:  8,
+		}
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.False(t, output.Skipped)
+		assert.Equal(t, int64(8), output.Sequence)
+		assert.Equal(t, int64(2), output.Gap)
+		balanceMock.AssertExpectations(t)
+	})
+
+	t.Run("should record the applied change in the balance history", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		existingBalance, _ := entity.NewBalance("account1", 100.0)
+		occurredAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+		balanceMock.On("UpdateBalance", mock.Anything).Return(nil)
+		historyMock.On("Save", mock.MatchedBy(func(change *entity.BalanceChange) bool {
+			return change.AccountId == "account1" &&
+				change.Balance == 90.0 &&
+				change.Sequence == 1 &&
+				change.TransactionId == "tx1" &&
+				change.OccurredAt.Equal(occurredAt)
+		})).Return(nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID:     "account1",
+			Balance:       90.0,
+			Sequence:      1,
+			TransactionID: "tx1",
+			OccurredAt:    occurredAt,
+		}
+
+		_, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		historyMock.AssertExpectations(t)
+	})
+
+	t.Run("should not record a skipped update twice", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		historyMock.On("HasSequence", "account1", int64(3)).Return(true, nil)
+		existingBalance, _ := entity.NewBalance("account1", 100.0)
+		existingBalance.Sequence = 3
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID: "account1",
+			Balance:   90.0,
+			Sequence:  3,
+		}
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.True(t, output.Skipped)
+		historyMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("should record a late update in the history without applying it", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		existingBalance, _ := entity.NewBalance("account1", 80.0)
+		existingBalance.Sequence = 3
+		occurredAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+		historyMock.On("HasSequence", "account1", int64(2)).Return(false, nil)
+		historyMock.On("Save", mock.MatchedBy(func(change *entity.BalanceChange) bool {
+			return change.Balance == 90.0 && change.Sequence == 2 && change.OccurredAt.Equal(occurredAt)
+		})).Return(nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID:     "account1",
+			Balance:       90.0,
+			Sequence:      2,
+			TransactionID: "tx2",
+			OccurredAt:    occurredAt,
+		}
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.True(t, output.Skipped)
+		assert.Equal(t, 80.0, output.Balance)
+		assert.Equal(t, int64(3), output.Sequence)
+		historyMock.AssertExpectations(t)
+		balanceMock.AssertNotCalled(t, "UpdateBalance")
+	})
+
+	t.Run("should return error when saving history fails", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		existingBalance, _ := entity.NewBalance("account1", 100.0)
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+		balanceMock.On("UpdateBalance", mock.Anything).Return(nil)
+		historyMock.On("Save", mock.Anything).Return(errors.New("database error"))
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, &mocks.InboxGatewayMock{}))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID: "account1",
+			Balance:   90.0,
+			Sequence:  1,
+		}
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, output)
+		assert.Equal(t, "database error", err.Error())
+	})
+	t.Run("should mark the event processed in the same unit of work", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		inboxMock := &mocks.InboxGatewayMock{}
+		existingBalance, _ := entity.NewBalance("account1", 100.0)
+
+		balanceMock.On("FindById", "account1").Return(existingBalance, nil)
+		balanceMock.On("UpdateBalance", mock.Anything).Return(nil)
+		historyMock.On("Save", mock.Anything).Return(nil)
+		inboxMock.On("Has", "account_balances", "balances/0/7:account1").Return(false, nil)
+		inboxMock.On("Save", mock.MatchedBy(func(processed *entity.ProcessedEvent) bool {
+			return processed.Consumer == "account_balances" && processed.EventId == "balances/0/7:account1"
+		})).Return(nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, inboxMock))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID: "account1",
+			Balance:   90.0,
+			Sequence:  1,
+			EventID:   "balances/0/7",
+		}
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.False(t, output.Skipped)
+		inboxMock.AssertExpectations(t)
+	})
+
+	t.Run("should skip events already in the inbox", func(t *testing.T) {
+		balanceMock := &mocks.BalanceGatewayMock{}
+		historyMock := &mocks.BalanceHistoryGatewayMock{}
+		inboxMock := &mocks.InboxGatewayMock{}
+		inboxMock.On("Has", "account_balances", "balances/0/7:account1").Return(true, nil)
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(newUowMock(balanceMock, historyMock, inboxMock))
+
+		input := update_account_balance.UpdateAccountBalanceInputDTO{
+			AccountID: "account1",
+			Balance:   90.0,
+			Sequence:  1,
+			EventID:   "balances/0/7",
+		}
+
+		output, err := useCase.Execute(context.Background(), input)
+
+		assert.Nil(t, err)
+		assert.True(t, output.Skipped)
+		balanceMock.AssertNotCalled(t, "FindById", mock.Anything)
+		inboxMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("should return error when the unit of work fails", func(t *testing.T) {
+		uowMock := &mocks.UowMock{}
+		uowMock.On("Do", mock.Anything, mock.Anything).Return(errors.New("commit failed"))
+
+		useCase := update_account_balance.NewUpdateAccountBalanceUseCase(uowMock)
+
+		output, err := useCase.Execute(context.Background(), update_account_balance.UpdateAccountBalanceInputDTO{AccountID: "account1"})
+
+		assert.Nil(t, output)
+		assert.Equal(t, "commit failed", err.Error())
+	})
+}
+
+func TestUpdateAccountBalanceUseCase_BalanceAndHistoryCommitTogether(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	migrator, err := database.NewMigrator(db, dialect.SQLite)
+	require.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	require.Nil(t, err)
+
+	balance, _ := entity.NewBalance("account1", 100.0)
+	require.Nil(t, database.NewBalanceDB(db, dialect.SQLite).Save(balance))
+	// Saving the history fails after the balance was updated
+	_, err = db.Exec("DROP TABLE account_balance_history")
+	require.Nil(t, err)
+
+	projectionUow := uow.NewUow(ctx, db)
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.BalanceGateway {
+		return database.NewBalanceDB(tx, dialect.SQLite)
+	})
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.BalanceHistoryGateway {
+		return database.NewBalanceHistoryDB(tx, dialect.SQLite)
+	})
+	uow.Register(projectionUow, func(tx *sql.Tx) gateway.InboxGateway {
+		return database.NewInboxDB(tx, dialect.SQLite)
+	})
+	useCase := update_account_balance.NewUpdateAccountBalanceUseCase(projectionUow)
+
+	_, err = useCase.Execute(ctx, update_account_balance.UpdateAccountBalanceInputDTO{
+		AccountID:  "account1",
+		Balance:    90.0,
+		Sequence:   1,
+		OccurredAt: time.Now(),
+	})
+	assert.NotNil(t, err)
+
+	saved, err := database.NewBalanceDB(db, dialect.SQLite).FindById("account1")
+	require.Nil(t, err)
+	assert.Equal(t, 100.0, saved.Balance)
+	assert.Equal(t, int64(0), saved.Sequence)
 }
@@ -2,21 +2,26 @@ package web
 
 import (
 	"balance/internal/usecase/get_account_balance"
-	"encoding/json"
+	"balance/internal/usecase/get_balance_history"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi"
 )
 
 type BalanceHandler struct {
 	GetAccountBalanceUseCase *get_account_balance.GetAccountBalanceUseCase
+	GetBalanceHistoryUseCase *get_balance_history.GetBalanceHistoryUseCase
 }
 
 func NewBalanceHandler(
 	getAccountBalanceUseCase *get_account_balance.GetAccountBalanceUseCase,
+	getBalanceHistoryUseCase *get_balance_history.GetBalanceHistoryUseCase,
 ) *BalanceHandler {
 	return &BalanceHandler{
 		GetAccountBalanceUseCase: getAccountBalanceUseCase,
+		GetBalanceHistoryUseCase: getBalanceHistoryUseCase,
 	}
 }
 
@@ -27,19 +32,49 @@ func (h *BalanceHandler) GetAccountBalance(w http.ResponseWriter, r *http.Reques
 		AccountID: accountID,
 	}
 
+	if at := r.URL.Query().Get("at"); at != "" {
+		parsed, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			http.Error(w, "invalid at parameter: expected RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		input.At = &parsed
+	}
+
 	output, err := h.GetAccountBalanceUseCase.Execute(input)
+	if errors.Is(err, get_account_balance.ErrAccountBalanceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	response, err := json.Marshal(output)
+	writeJSON(w, output)
+}
+
+func (h *BalanceHandler) GetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	input := get_balance_history.GetBalanceHistoryInputDTO{
+		AccountID: chi.URLParam(r, "account_id"),
+	}
+
+	var err error
+	input.Limit, input.Offset, err = parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.GetBalanceHistoryUseCase.Execute(input)
+	if errors.Is(err, get_balance_history.ErrBalanceHistoryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	writeJSON(w, output)
 }
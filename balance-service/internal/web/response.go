@@ -0,0 +1,36 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+func writeJSON(w http.ResponseWriter, output interface{}) {
+	response, err := json.Marshal(output)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// parsePagination reads the optional limit and offset query parameters.
+// Missing values are returned as zero so use cases can apply their defaults.
+func parsePagination(r *http.Request) (limit int, offset int, err error) {
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			return 0, 0, errors.New("invalid limit parameter")
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil {
+			return 0, 0, errors.New("invalid offset parameter")
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"balance/internal/usecase/list_account_transactions"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+type TransactionHandler struct {
+	ListAccountTransactionsUseCase *list_account_transactions.ListAccountTransactionsUseCase
+}
+
+func NewTransactionHandler(
+	listAccountTransactionsUseCase *list_account_transactions.ListAccountTransactionsUseCase,
+) *TransactionHandler {
+	return &TransactionHandler{
+		ListAccountTransactionsUseCase: listAccountTransactionsUseCase,
+	}
+}
+
+func (h *TransactionHandler) ListAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	input := list_account_transactions.ListAccountTransactionsInputDTO{
+		AccountID: chi.URLParam(r, "id"),
+	}
+
+	var err error
+	input.Limit, input.Offset, err = parsePagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.ListAccountTransactionsUseCase.Execute(input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, output)
+}
@@ -0,0 +1,140 @@
+package dialect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect covers the SQL that differs between the databases the services
+// run on. Queries are written with ? placeholders and rebound per dialect.
+type Dialect interface {
+	// Name is the URL scheme selecting the dialect, e.g. "postgres".
+	Name() string
+	// DriverName is the database/sql driver the dialect runs on.
+	DriverName() string
+	// Rebind rewrites the ? placeholders of query to the dialect's own.
+	Rebind(query string) string
+	// Upsert returns an INSERT of columns into table that, when a row with
+	// the same key exists, sets the update columns to the inserted values
+	// instead, or leaves the row alone when update is empty.
+	Upsert(table string, columns, key, update []string) string
+	// ForUpdate returns the clause appended to a SELECT to lock the rows it
+	// reads until the transaction ends, or "" when the database locks
+	// whole transactions instead.
+	ForUpdate() string
+	// TimestampType is the column type storing a point in time.
+	TimestampType() string
+	// LockTable returns a statement that keeps other transactions from
+	// writing to table until the transaction running it ends. Plain reads
+	// go on where the database allows them to.
+	LockTable(table string) string
+}
+
+var (
+	MySQL    Dialect = mysqlDialect{}
+	Postgres Dialect = postgresDialect{}
+	SQLite   Dialect = sqliteDialect{}
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string               { return "mysql" }
+func (mysqlDialect) DriverName() string         { return "mysql" }
+func (mysqlDialect) Rebind(query string) string { return query }
+func (mysqlDialect) ForUpdate() string          { return " FOR UPDATE" }
+func (mysqlDialect) TimestampType() string      { return "DATETIME" }
+
+// LockTable locks every row, and the gaps between them so inserts wait too;
+// LOCK TABLES would commit the transaction instead.
+func (mysqlDialect) LockTable(table string) string {
+	return "SELECT 1 FROM " + table + " FOR UPDATE"
+}
+
+func (mysqlDialect) Upsert(table string, columns, key, update []string) string {
+	assignments := make([]string, 0, len(update))
+	for _, column := range update {
+		assignments = append(assignments, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+	if len(assignments) == 0 {
+		// Assigning a key column to itself changes nothing, unlike INSERT
+		// IGNORE which also swallows errors other than duplicates
+		assignments = append(assignments, fmt.Sprintf("%s = %s", key[0], key[0]))
+	}
+	return insert(table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string          { return "postgres" }
+func (postgresDialect) DriverName() string    { return "pgx" }
+func (postgresDialect) ForUpdate() string     { return " FOR UPDATE" }
+func (postgresDialect) TimestampType() string { return "TIMESTAMPTZ" }
+
+func (postgresDialect) LockTable(table string) string {
+	return "LOCK TABLE " + table + " IN SHARE ROW EXCLUSIVE MODE"
+}
+
+// Rebind numbers the placeholders $1, $2, ..., skipping question marks in
+// quoted strings and identifiers.
+func (postgresDialect) Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (d postgresDialect) Upsert(table string, columns, key, update []string) string {
+	return d.Rebind(onConflict(table, columns, key, update))
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string               { return "sqlite" }
+func (sqliteDialect) DriverName() string         { return "sqlite" }
+func (sqliteDialect) Rebind(query string) string { return query }
+func (sqliteDialect) ForUpdate() string          { return "" }
+func (sqliteDialect) TimestampType() string      { return "DATETIME" }
+
+// LockTable takes the lock of the whole database, which the first write of a
+// transaction does even when it changes no row.
+func (sqliteDialect) LockTable(table string) string {
+	return "DELETE FROM " + table + " WHERE 1 = 0"
+}
+
+func (sqliteDialect) Upsert(table string, columns, key, update []string) string {
+	return onConflict(table, columns, key, update)
+}
+
+func insert(table string, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+}
+
+// onConflict builds the standard upsert shared by PostgreSQL and SQLite.
+func onConflict(table string, columns, key, update []string) string {
+	query := insert(table, columns) + " ON CONFLICT (" + strings.Join(key, ", ") + ")"
+	if len(update) == 0 {
+		return query + " DO NOTHING"
+	}
+	assignments := make([]string, 0, len(update))
+	for _, column := range update {
+		assignments = append(assignments, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+	return query + " DO UPDATE SET " + strings.Join(assignments, ", ")
+}
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// ContentTypeCloudEventsJSON marks a structured-mode CloudEvent, where the
+	// whole event is a JSON envelope in the message value.
+	ContentTypeCloudEventsJSON = "application/cloudevents+json"
+	// CloudEventTypePrefix turns an event name into a CloudEvents type, e.g.
+	// TransactionCreated into wallet.events.TransactionCreated.
+	CloudEventTypePrefix = "wallet.events."
+)
+
+// Message headers of the CloudEvents Kafka protocol binding. In binary mode
+// the attributes travel as ce_ headers and the value holds only the data,
+// encoded as content-type says.
+const (
+	HeaderContentType        = "content-type"
+	HeaderCloudEventsVersion = "ce_specversion"
+	HeaderCloudEventsID      = "ce_id"
+	HeaderCloudEventsSource  = "ce_source"
+	HeaderCloudEventsType    = "ce_type"
+	HeaderCloudEventsTime    = "ce_time"
+)
+
+var ErrNotCloudEvent = errors.New("message is not a CloudEvent")
+
+// CloudEvent is the structured-mode JSON envelope of an event. Data holds
+// JSON data as is; other content types are carried in DataBase64.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+func CloudEventType(name string) string {
+	return CloudEventTypePrefix + name
+}
+
+// EventName returns the event name of a CloudEvents type.
+func EventName(eventType string) (string, error) {
+	name, ok := strings.CutPrefix(eventType, CloudEventTypePrefix)
+	if !ok || name == "" {
+		return "", fmt.Errorf("unknown event type %q", eventType)
+	}
+	return name, nil
+}
+
+// CloudEventsEncoder publishes events as CloudEvents 1.0 from Source, with
+// their payload encoded by Codec. The id is the event's own, so publishing an
+// event again, e.g. on a retry, lets consumers tell it is the same event.
+type CloudEventsEncoder struct {
+	Source string
+	// Structured puts the whole event in the message value instead of
+	// carrying its attributes in headers.
+	Structured bool
+	Codec      Codec
+}
+
+func NewCloudEventsEncoder(source string, structured bool, codec Codec) *CloudEventsEncoder {
+	return &CloudEventsEncoder{
+		Source:     source,
+		Structured: structured,
+		Codec:      codec,
+	}
+}
+
+// Encode returns the message value and headers carrying event.
+func (e *CloudEventsEncoder) Encode(event EventInterface) ([]byte, map[string]string, error) {
+	if event.GetID() == "" {
+		return nil, nil, fmt.Errorf("event %s has no id", event.GetName())
+	}
+	data, err := e.Codec.MarshalPayload(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	ce := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.GetID(),
+		Source:          e.Source,
+		Type:            CloudEventType(event.GetName()),
+		Time:            event.GetDateTime().UTC().Format(time.RFC3339Nano),
+		DataContentType: e.Codec.ContentType(),
+	}
+
+	if !e.Structured {
+		return data, map[string]string{
+			HeaderContentType:        ce.DataContentType,
+			HeaderCloudEventsVersion: ce.SpecVersion,
+			HeaderCloudEventsID:      ce.ID,
+			HeaderCloudEventsSource:  ce.Source,
+			HeaderCloudEventsType:    ce.Type,
+			HeaderCloudEventsTime:    ce.Time,
+		}, nil
+	}
+
+	if ce.DataContentType == ContentTypeJSON {
+		ce.Data = data
+	} else {
+		ce.DataBase64 = data
+	}
+	value, err := json.Marshal(ce)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, map[string]string{HeaderContentType: ContentTypeCloudEventsJSON}, nil
+}
+
+// Attributes describe a decoded event. ID, Source and Time are the
+// CloudEvents attributes it was published with; they are empty for events
+// published before CloudEvents.
+type Attributes struct {
+	Name   string
+	ID     string
+	Source string
+	Time   time.Time
+}
+
+// DecodeMessage returns the attributes and payload of the event in a message,
+// whether it is a binary or structured CloudEvent or an event published
+// before CloudEvents, encoded as a whole by the codec of its content type.
+func DecodeMessage(headers map[string]string, value []byte) (Attributes, interface{}, error) {
+	if !IsCloudEvent(headers) {
+		return decodeLegacy(headers, value)
+	}
+	if mediaType(headers[HeaderContentType]) == ContentTypeCloudEventsJSON {
+		return decodeStructured(value)
+	}
+	return decodeBinary(headers, value)
+}
+
+// IsCloudEvent reports whether a message with headers carries a binary or
+// structured CloudEvent rather than an event published before CloudEvents.
+func IsCloudEvent(headers map[string]string) bool {
+	if mediaType(headers[HeaderContentType]) == ContentTypeCloudEventsJSON {
+		return true
+	}
+	_, ok := headers[HeaderCloudEventsVersion]
+	return ok
+}
+
+func decodeLegacy(headers map[string]string, value []byte) (Attributes, interface{}, error) {
+	codec, err := CodecFor(mediaType(headers[HeaderContentType]))
+	if err != nil {
+		return Attributes{}, nil, err
+	}
+	name, payload, err := codec.Unmarshal(value)
+	if err != nil {
+		return Attributes{}, nil, err
+	}
+	return Attributes{Name: name}, payload, nil
+}
+
+func decodeBinary(headers map[string]string, value []byte) (Attributes, interface{}, error) {
+	ce := CloudEvent{
+		SpecVersion:     headers[HeaderCloudEventsVersion],
+		ID:              headers[HeaderCloudEventsID],
+		Source:          headers[HeaderCloudEventsSource],
+		Type:            headers[HeaderCloudEventsType],
+		Time:            headers[HeaderCloudEventsTime],
+		DataContentType: mediaType(headers[HeaderContentType]),
+	}
+	return decodeCloudEvent(ce, value)
+}
+
+func decodeStructured(value []byte) (Attributes, interface{}, error) {
+	var ce CloudEvent
+	if err := json.Unmarshal(value, &ce); err != nil {
+		return Attributes{}, nil, fmt.Errorf("%w: %v", ErrNotCloudEvent, err)
+	}
+	data := []byte(ce.Data)
+	if ce.DataBase64 != nil {
+		data = ce.DataBase64
+	}
+	ce.DataContentType = mediaType(ce.DataContentType)
+	return decodeCloudEvent(ce, data)
+}
+
+func decodeCloudEvent(ce CloudEvent, data []byte) (Attributes, interface{}, error) {
+	if ce.SpecVersion != CloudEventsSpecVersion {
+		return Attributes{}, nil, fmt.Errorf("%w: unsupported specversion %q", ErrNotCloudEvent, ce.SpecVersion)
+	}
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return Attributes{}, nil, fmt.Errorf("%w: id, source and type are required", ErrNotCloudEvent)
+	}
+	attributes := Attributes{ID: ce.ID, Source: ce.Source}
+	if ce.Time != "" {
+		at, err := time.Parse(time.RFC3339Nano, ce.Time)
+		if err != nil {
+			return Attributes{}, nil, fmt.Errorf("%w: invalid time %q", ErrNotCloudEvent, ce.Time)
+		}
+		attributes.Time = at
+	}
+	name, err := EventName(ce.Type)
+	if err != nil {
+		return Attributes{}, nil, err
+	}
+	attributes.Name = name
+	codec, err := CodecFor(ce.DataContentType)
+	if err != nil {
+		return Attributes{}, nil, err
+	}
+	payload, err := codec.UnmarshalPayload(name, data)
+	if err != nil {
+		return Attributes{}, nil, err
+	}
+	return attributes, payload, nil
+}
+
+// mediaType drops parameters such as charset from a content type.
+func mediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(mediaType)
+}
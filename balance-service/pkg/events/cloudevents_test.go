@@ -0,0 +1,173 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventName(t *testing.T) {
+	name, err := EventName(CloudEventType("BalanceUpdated"))
+	assert.Nil(t, err)
+	assert.Equal(t, "BalanceUpdated", name)
+
+	_, err = EventName("com.example.OrderPlaced")
+	assert.EqualError(t, err, `unknown event type "com.example.OrderPlaced"`)
+}
+
+func TestCloudEventsEncoderBinary(t *testing.T) {
+	event := &testEvent{ID: "e1", Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}}
+
+	value, headers, err := NewCloudEventsEncoder("/wallet-service", false, JSONCodec{}).Encode(event)
+
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"account_id":"a1","balance":90.5}`, string(value))
+	assert.Equal(t, "application/json", headers[HeaderContentType])
+	assert.Equal(t, "1.0", headers[HeaderCloudEventsVersion])
+	assert.Equal(t, "/wallet-service", headers[HeaderCloudEventsSource])
+	assert.Equal(t, "wallet.events.BalanceUpdated", headers[HeaderCloudEventsType])
+	assert.Equal(t, "e1", headers[HeaderCloudEventsID])
+	assert.NotEmpty(t, headers[HeaderCloudEventsTime])
+
+	attributes, payload, err := DecodeMessage(headers, value)
+	assert.Nil(t, err)
+	assert.Equal(t, "BalanceUpdated", attributes.Name)
+	assert.Equal(t, map[string]interface{}{"account_id": "a1", "balance": 90.5}, payload)
+}
+
+func TestCloudEventsEncoderID(t *testing.T) {
+	t.Run("keeps the id of an event encoded again", func(t *testing.T) {
+		event := &testEvent{ID: "e1", Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}}
+		encoder := NewCloudEventsEncoder("/wallet-service", false, JSONCodec{})
+
+		_, first, err := encoder.Encode(event)
+		require.Nil(t, err)
+		_, second, err := encoder.Encode(event)
+		require.Nil(t, err)
+
+		assert.Equal(t, first[HeaderCloudEventsID], second[HeaderCloudEventsID])
+	})
+
+	t.Run("refuses an event without an id", func(t *testing.T) {
+		event := &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}}
+
+		_, _, err := NewCloudEventsEncoder("/wallet-service", true, JSONCodec{}).Encode(event)
+
+		assert.EqualError(t, err, "event BalanceUpdated has no id")
+	})
+}
+
+func TestCloudEventsEncoderStructured(t *testing.T) {
+	t.Run("embeds JSON data", func(t *testing.T) {
+		event := &testEvent{ID: "e1", Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}}
+
+		value, headers, err := NewCloudEventsEncoder("/wallet-service", true, JSONCodec{}).Encode(event)
+
+		require.Nil(t, err)
+		assert.Equal(t, map[string]string{HeaderContentType: "application/cloudevents+json"}, headers)
+		var ce map[string]interface{}
+		require.Nil(t, json.Unmarshal(value, &ce))
+		assert.Equal(t, "1.0", ce["specversion"])
+		assert.Equal(t, "e1", ce["id"])
+		assert.Equal(t, "wallet.events.BalanceUpdated", ce["type"])
+		assert.Equal(t, "application/json", ce["datacontenttype"])
+		assert.Equal(t, map[string]interface{}{"account_id": "a1", "balance": 90.5}, ce["data"])
+
+		attributes, payload, err := DecodeMessage(headers, value)
+		assert.Nil(t, err)
+		assert.Equal(t, "BalanceUpdated", attributes.Name)
+		assert.Equal(t, map[string]interface{}{"account_id": "a1", "balance": 90.5}, payload)
+	})
+
+	t.Run("carries other content types as base64", func(t *testing.T) {
+		event := &testEvent{ID: "e1", Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}}
+
+		value, headers, err := NewCloudEventsEncoder("/wallet-service", true, ProtobufCodec{}).Encode(event)
+
+		require.Nil(t, err)
+		var ce map[string]interface{}
+		require.Nil(t, json.Unmarshal(value, &ce))
+		assert.Equal(t, "application/protobuf", ce["datacontenttype"])
+		assert.NotEmpty(t, ce["data_base64"])
+		assert.NotContains(t, ce, "data")
+
+		attributes, payload, err := DecodeMessage(headers, value)
+		assert.Nil(t, err)
+		assert.Equal(t, "BalanceUpdated", attributes.Name)
+		assert.Equal(t, json.Number("90.50"), payload.(map[string]interface{})["balance"])
+	})
+}
+
+func TestIsCloudEvent(t *testing.T) {
+	assert.True(t, IsCloudEvent(map[string]string{HeaderContentType: ContentTypeJSON, HeaderCloudEventsVersion: "1.0"}))
+	assert.True(t, IsCloudEvent(map[string]string{HeaderContentType: "application/cloudevents+json; charset=utf-8"}))
+	assert.False(t, IsCloudEvent(map[string]string{HeaderContentType: ContentTypeJSON}))
+	assert.False(t, IsCloudEvent(nil))
+}
+
+func TestDecodeMessage(t *testing.T) {
+	t.Run("reads events published before CloudEvents", func(t *testing.T) {
+		attributes, payload, err := DecodeMessage(nil, []byte(`{"name":"BalanceUpdated","payload":{"account_id":"a1"}}`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "BalanceUpdated", attributes.Name)
+		assert.Equal(t, map[string]interface{}{"account_id": "a1"}, payload)
+		assert.Empty(t, attributes.ID)
+		assert.True(t, attributes.Time.IsZero())
+	})
+
+	t.Run("accepts content type parameters", func(t *testing.T) {
+		value := []byte(`{"specversion":"1.0","id":"1","source":"/gateway","type":"wallet.events.BalanceUpdated","data":{"account_id":"a1"}}`)
+
+		attributes, _, err := DecodeMessage(map[string]string{HeaderContentType: "application/cloudevents+json; charset=utf-8"}, value)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "BalanceUpdated", attributes.Name)
+	})
+
+	t.Run("returns the attributes the event was published with", func(t *testing.T) {
+		at := time.Date(2025, 3, 3, 10, 0, 0, 123456789, time.UTC)
+		event := &testEvent{ID: "e1", Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}, DateTime: at}
+
+		for _, structured := range []bool{false, true} {
+			value, headers, err := NewCloudEventsEncoder("/wallet-service", structured, JSONCodec{}).Encode(event)
+			require.Nil(t, err)
+
+			attributes, _, err := DecodeMessage(headers, value)
+
+			require.Nil(t, err)
+			assert.Equal(t, Attributes{Name: "BalanceUpdated", ID: "e1", Source: "/wallet-service", Time: at}, attributes)
+		}
+	})
+
+	t.Run("rejects an invalid time", func(t *testing.T) {
+		value := []byte(`{"specversion":"1.0","id":"1","source":"/gateway","type":"wallet.events.BalanceUpdated","time":"yesterday","data":{}}`)
+
+		_, _, err := DecodeMessage(map[string]string{HeaderContentType: ContentTypeCloudEventsJSON}, value)
+
+		assert.ErrorIs(t, err, ErrNotCloudEvent)
+	})
+
+	t.Run("rejects other spec versions", func(t *testing.T) {
+		value := []byte(`{"specversion":"0.3","id":"1","source":"/gateway","type":"wallet.events.BalanceUpdated","data":{}}`)
+
+		_, _, err := DecodeMessage(map[string]string{HeaderContentType: ContentTypeCloudEventsJSON}, value)
+
+		assert.ErrorIs(t, err, ErrNotCloudEvent)
+	})
+
+	t.Run("requires id, source and type", func(t *testing.T) {
+		headers := map[string]string{
+			HeaderContentType:        ContentTypeJSON,
+			HeaderCloudEventsVersion: "1.0",
+			HeaderCloudEventsType:    "wallet.events.BalanceUpdated",
+		}
+
+		_, _, err := DecodeMessage(headers, []byte(`{}`))
+
+		assert.ErrorIs(t, err, ErrNotCloudEvent)
+	})
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Content types of encoded events, carried in the content-type header of
+// every message so consumers pick the matching codec.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+// Codec encodes events for the wire and decodes them back into their name
+// and a generic payload, which handlers turn into their own types. Marshal
+// and Unmarshal carry the name along with the payload; MarshalPayload and
+// UnmarshalPayload handle the payload alone, for formats such as CloudEvents
+// that carry the name elsewhere.
+type Codec interface {
+	ContentType() string
+	Marshal(event EventInterface) ([]byte, error)
+	Unmarshal(data []byte) (name string, payload interface{}, err error)
+	MarshalPayload(event EventInterface) ([]byte, error)
+	UnmarshalPayload(name string, data []byte) (interface{}, error)
+}
+
+// CodecFor returns the codec of a content type. Messages published before
+// the header was introduced have none and are JSON.
+func CodecFor(contentType string) (Codec, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return JSONCodec{}, nil
+	case ContentTypeProtobuf:
+		return ProtobufCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+// JSONCodec encodes an event as {"name": ..., "payload": ...}.
+type JSONCodec struct{}
+
+type jsonEnvelope struct {
+	Name    string      `json:"name"`
+	Payload interface{} `json:"payload"`
+}
+
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (JSONCodec) Marshal(event EventInterface) ([]byte, error) {
+	return json.Marshal(jsonEnvelope{Name: event.GetName(), Payload: event.GetPayload()})
+}
+
+func (JSONCodec) Unmarshal(data []byte) (string, interface{}, error) {
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", nil, err
+	}
+	return envelope.Name, envelope.Payload, nil
+}
+
+func (JSONCodec) MarshalPayload(event EventInterface) ([]byte, error) {
+	return json.Marshal(event.GetPayload())
+}
+
+func (JSONCodec) UnmarshalPayload(name string, data []byte) (interface{}, error) {
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
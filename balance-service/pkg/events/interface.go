@@ -1,25 +1,46 @@
 package events
 
 import (
-	"sync"
+	"context"
 	"time"
 )
 
+// EventInterface is an event as handlers see it. Events are not modified once
+// created, so concurrent handlers all see the payload it was created with, and
+// GetID returns the id assigned at creation however often the event is
+// published.
 type EventInterface interface {
+	GetID() string
 	GetName() string
 	GetDateTime() time.Time
 	GetPayload() interface{}
-	SetPayload(payload interface{})
+}
+
+// EventFactory creates a new event carrying payload, so every dispatch gets
+// its own instance.
+type EventFactory func(payload interface{}) EventInterface
+
+// NewFactory adapts the constructor of an event type to EventFactory.
+func NewFactory[E EventInterface](newEvent func(payload interface{}) E) EventFactory {
+	return func(payload interface{}) EventInterface {
+		return newEvent(payload)
+	}
 }
 
 type EventHandlerInterface interface {
-	Handle(event EventInterface, wg *sync.WaitGroup)
+	Handle(ctx context.Context, event EventInterface) error
 }
 
 type EventDispatcherInterface interface {
-	Register(eventName string, handler EventHandlerInterface) error
-	Dispatch(event EventInterface) error
+	Register(eventName string, handler EventHandlerInterface, middleware ...Middleware) error
+	Dispatch(ctx context.Context, event EventInterface) error
 	Remove(eventName string, handler EventHandlerInterface) error
 	Has(eventName string, handler EventHandlerInterface) bool
 	Clear()
 }
+
+// PartitionKeyer is implemented by events, or by their payloads, that must be
+// delivered in order relative to other events with the same key.
+type PartitionKeyer interface {
+	PartitionKey() string
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+var ErrHandlerPanicked = errors.New("handler panicked")
+
+// HandlerFunc adapts a function to EventHandlerInterface. Register a pointer
+// to it, since the dispatcher tells handlers apart by comparing them.
+type HandlerFunc func(ctx context.Context, event EventInterface) error
+
+func (f HandlerFunc) Handle(ctx context.Context, event EventInterface) error {
+	return f(ctx, event)
+}
+
+// Middleware wraps a handler with behaviour such as retries or logging.
+type Middleware func(next EventHandlerInterface) EventHandlerInterface
+
+// Chain composes middleware so the first one is the outermost.
+func Chain(middleware ...Middleware) Middleware {
+	return func(next EventHandlerInterface) EventHandlerInterface {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+		return next
+	}
+}
+
+// Recover turns a panic in the handler into an error wrapping
+// ErrHandlerPanicked.
+func Recover() Middleware {
+	return func(next EventHandlerInterface) EventHandlerInterface {
+		return HandlerFunc(func(ctx context.Context, event EventInterface) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("%s handler panicked: %v\n%s", event.GetName(), r, debug.Stack())
+					err = fmt.Errorf("%w: %v", ErrHandlerPanicked, r)
+				}
+			}()
+			return next.Handle(ctx, event)
+		})
+	}
+}
+
+// Retry calls the handler up to attempts times, doubling the wait after each
+// failure from backoff. It gives up early when ctx is done.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next EventHandlerInterface) EventHandlerInterface {
+		return HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			var err error
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+				if err = next.Handle(ctx, event); err == nil || attempt >= attempts {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return errors.Join(err, ctx.Err())
+				case <-time.After(wait):
+				}
+				wait *= 2
+			}
+		})
+	}
+}
+
+// Timeout cancels the context of each handler call after d.
+func Timeout(d time.Duration) Middleware {
+	return func(next EventHandlerInterface) EventHandlerInterface {
+		return HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next.Handle(ctx, event)
+		})
+	}
+}
+
+// Logging logs failed handler calls with how long they took.
+func Logging() Middleware {
+	return func(next EventHandlerInterface) EventHandlerInterface {
+		return HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			start := time.Now()
+			err := next.Handle(ctx, event)
+			if err != nil {
+				log.Printf("%s handler failed after %s: %v", event.GetName(), time.Since(start), err)
+			}
+			return err
+		})
+	}
+}
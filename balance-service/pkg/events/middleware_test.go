@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	t.Run("retries until the handler succeeds", func(t *testing.T) {
+		calls := 0
+		handler := Retry(3, time.Millisecond)(HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			calls++
+			if calls < 2 {
+				return errors.New("broker unavailable")
+			}
+			return nil
+		}))
+
+		assert.Nil(t, handler.Handle(context.Background(), &TestEvent{Name: "test"}))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("returns the last error once attempts are exhausted", func(t *testing.T) {
+		calls := 0
+		handler := Retry(3, time.Millisecond)(HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			calls++
+			return errors.New("broker unavailable")
+		}))
+
+		assert.EqualError(t, handler.Handle(context.Background(), &TestEvent{Name: "test"}), "broker unavailable")
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		handler := Retry(3, time.Hour)(HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			calls++
+			return errors.New("broker unavailable")
+		}))
+
+		assert.ErrorIs(t, handler.Handle(ctx, &TestEvent{Name: "test"}), context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	handler := Timeout(time.Millisecond)(HandlerFunc(func(ctx context.Context, event EventInterface) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	assert.ErrorIs(t, handler.Handle(context.Background(), &TestEvent{Name: "test"}), context.DeadlineExceeded)
+}
+
+func TestRecover(t *testing.T) {
+	handler := Recover()(HandlerFunc(func(ctx context.Context, event EventInterface) error {
+		panic("boom")
+	}))
+
+	err := handler.Handle(context.Background(), &TestEvent{Name: "test"})
+
+	assert.ErrorIs(t, err, ErrHandlerPanicked)
+	assert.ErrorContains(t, err, "boom")
+}
@@ -0,0 +1,14 @@
+package events
+
+// PartitionKey returns the key an event should be published with: the
+// event's own key, else its payload's, else nil when the event carries no
+// ordering constraint.
+func PartitionKey(event EventInterface) []byte {
+	if keyer, ok := event.(PartitionKeyer); ok && keyer.PartitionKey() != "" {
+		return []byte(keyer.PartitionKey())
+	}
+	if keyer, ok := event.GetPayload().(PartitionKeyer); ok && keyer.PartitionKey() != "" {
+		return []byte(keyer.PartitionKey())
+	}
+	return nil
+}
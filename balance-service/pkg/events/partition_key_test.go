@@ -0,0 +1,43 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type keyedPayload struct {
+	AccountID string
+}
+
+func (p keyedPayload) PartitionKey() string {
+	return p.AccountID
+}
+
+type keyedEvent struct {
+	TestEvent
+	Key string
+}
+
+func (e *keyedEvent) PartitionKey() string {
+	return e.Key
+}
+
+func TestPartitionKey(t *testing.T) {
+	t.Run("uses the event key first", func(t *testing.T) {
+		event := &keyedEvent{TestEvent: TestEvent{Payload: keyedPayload{AccountID: "payload"}}, Key: "event"}
+
+		assert.Equal(t, []byte("event"), PartitionKey(event))
+	})
+
+	t.Run("falls back to the payload key", func(t *testing.T) {
+		event := &TestEvent{Payload: keyedPayload{AccountID: "account1"}}
+
+		assert.Equal(t, []byte("account1"), PartitionKey(event))
+	})
+
+	t.Run("returns nil for unkeyed events", func(t *testing.T) {
+		assert.Nil(t, PartitionKey(&TestEvent{Payload: map[string]interface{}{}}))
+		assert.Nil(t, PartitionKey(&keyedEvent{TestEvent: TestEvent{}}))
+	})
+}
@@ -0,0 +1,347 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoKind is how a payload field is laid out on the wire.
+type protoKind int
+
+const (
+	protoString protoKind = iota
+	// protoMoney is a JSON number carried as a decimal string with
+	// moneyDecimals places.
+	protoMoney
+	protoInt64
+	// protoTimestamp is an RFC 3339 string carried as a
+	// google.protobuf.Timestamp.
+	protoTimestamp
+)
+
+type protoField struct {
+	Number protowire.Number
+	Name   string
+	Kind   protoKind
+}
+
+type protoMessage struct {
+	// Number is the field of the payload in the Event envelope.
+	Number protowire.Number
+	Fields []protoField
+}
+
+// protoMessages mirrors schemas/events.v1.proto, which
+// TestProtoMessagesMatchSchema checks. Field numbers are never reused once
+// published.
+//
+// The per-transfer BalanceUpdated of balance_updated.v1.json, with its
+// *_account_id_from and *_account_id_to fields, predates Protobuf events and
+// has no message: it is only ever decoded from JSON, and encoding it as
+// Protobuf fails.
+var protoMessages = map[string]protoMessage{
+	"TransactionCreated": {Number: 10, Fields: []protoField{
+		{1, "id", protoString},
+		{2, "account_id_from", protoString},
+		{3, "account_id_to", protoString},
+		{4, "amount", protoMoney},
+		{5, "created_at", protoTimestamp},
+	}},
+	"BalanceUpdated": {Number: 11, Fields: []protoField{
+		{1, "account_id", protoString},
+		{2, "balance", protoMoney},
+		{3, "sequence", protoInt64},
+		{4, "transaction_id", protoString},
+		{5, "created_at", protoTimestamp},
+	}},
+}
+
+const protoEventName protowire.Number = 1
+
+// moneyDecimals is the number of decimal places of the amounts and balances
+// carried by protobuf events, the minor units of the currency.
+const moneyDecimals = 2
+
+// ProtobufCodec encodes events as described in schemas/events.v1.proto.
+// Decoded payloads have the same shape as JSON-decoded ones, with numbers as
+// json.Number so amounts keep their exact decimal text.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (c ProtobufCodec) Marshal(event EventInterface) ([]byte, error) {
+	message, ok := protoMessages[event.GetName()]
+	if !ok {
+		return nil, fmt.Errorf("no protobuf message for event %q", event.GetName())
+	}
+	payload, err := c.MarshalPayload(event)
+	if err != nil {
+		return nil, err
+	}
+	data := protowire.AppendTag(nil, protoEventName, protowire.BytesType)
+	data = protowire.AppendString(data, event.GetName())
+	data = protowire.AppendTag(data, message.Number, protowire.BytesType)
+	return protowire.AppendBytes(data, payload), nil
+}
+
+// MarshalPayload encodes the payload of event as its own protobuf message,
+// e.g. TransactionCreated, without the Event envelope.
+func (ProtobufCodec) MarshalPayload(event EventInterface) ([]byte, error) {
+	message, ok := protoMessages[event.GetName()]
+	if !ok {
+		return nil, fmt.Errorf("no protobuf message for event %q", event.GetName())
+	}
+	fields, err := payloadFields(event.GetPayload())
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	for _, field := range message.Fields {
+		value, ok := fields[field.Name]
+		delete(fields, field.Name)
+		if !ok || value == nil {
+			continue
+		}
+		payload, err = appendProtoField(payload, field, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", event.GetName(), field.Name, err)
+		}
+	}
+	if len(fields) > 0 {
+		unknown := make([]string, 0, len(fields))
+		for name := range fields {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s has no protobuf field for %s", event.GetName(), strings.Join(unknown, ", "))
+	}
+	return payload, nil
+}
+
+func (c ProtobufCodec) Unmarshal(data []byte) (string, interface{}, error) {
+	name := ""
+	payloads := map[protowire.Number][]byte{}
+	err := consumeProtoFields(data, func(number protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		if number == protoEventName {
+			name = string(value)
+		} else {
+			payloads[number] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	message, ok := protoMessages[name]
+	if !ok {
+		return "", nil, fmt.Errorf("no protobuf message for event %q", name)
+	}
+	payload, err := c.UnmarshalPayload(name, payloads[message.Number])
+	if err != nil {
+		return "", nil, err
+	}
+	return name, payload, nil
+}
+
+// UnmarshalPayload decodes the payload message of the named event.
+func (ProtobufCodec) UnmarshalPayload(name string, data []byte) (interface{}, error) {
+	message, ok := protoMessages[name]
+	if !ok {
+		return nil, fmt.Errorf("no protobuf message for event %q", name)
+	}
+	byNumber := map[protowire.Number]protoField{}
+	for _, field := range message.Fields {
+		byNumber[field.Number] = field
+	}
+	fields := map[string]interface{}{}
+	err := consumeProtoFields(data, func(number protowire.Number, typ protowire.Type, value []byte) error {
+		field, ok := byNumber[number]
+		if !ok {
+			// Fields added by newer producers are skipped
+			return nil
+		}
+		decoded, err := decodeProtoField(field, typ, value)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", name, field.Name, err)
+		}
+		fields[field.Name] = decoded
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// payloadFields turns a payload into its JSON fields, keeping numbers as
+// written.
+func payloadFields(payload interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	fields := map[string]interface{}{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func appendProtoField(b []byte, field protoField, value interface{}) ([]byte, error) {
+	switch field.Kind {
+	case protoString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("not a string")
+		}
+		if s == "" {
+			return b, nil
+		}
+		b = protowire.AppendTag(b, field.Number, protowire.BytesType)
+		return protowire.AppendString(b, s), nil
+	case protoMoney:
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, errors.New("not a number")
+		}
+		amount, err := formatMoney(n)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, field.Number, protowire.BytesType)
+		return protowire.AppendString(b, amount), nil
+	case protoInt64:
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, errors.New("not a number")
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			return b, nil
+		}
+		b = protowire.AppendTag(b, field.Number, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(i)), nil
+	case protoTimestamp:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("not a timestamp")
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		var timestamp []byte
+		if seconds := t.Unix(); seconds != 0 {
+			timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(seconds))
+		}
+		if nanos := t.Nanosecond(); nanos != 0 {
+			timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(nanos))
+		}
+		b = protowire.AppendTag(b, field.Number, protowire.BytesType)
+		return protowire.AppendBytes(b, timestamp), nil
+	default:
+		return nil, fmt.Errorf("unknown field kind %d", field.Kind)
+	}
+}
+
+// formatMoney rounds an amount to the minor units of the currency, so the
+// binary float it was read into never shows, e.g. 0.30000000000000004 is
+// carried as 0.30.
+func formatMoney(n json.Number) (string, error) {
+	f, err := n.Float64()
+	if err != nil {
+		return "", err
+	}
+	scale := math.Pow10(moneyDecimals)
+	return strconv.FormatFloat(math.Round(f*scale)/scale, 'f', moneyDecimals, 64), nil
+}
+
+func decodeProtoField(field protoField, typ protowire.Type, value []byte) (interface{}, error) {
+	switch field.Kind {
+	case protoString:
+		return string(value), nil
+	case protoMoney:
+		n := json.Number(value)
+		if _, err := n.Float64(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case protoInt64:
+		if typ != protowire.VarintType {
+			return nil, errors.New("not a varint")
+		}
+		v, n := protowire.ConsumeVarint(value)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		return json.Number(fmt.Sprint(int64(v))), nil
+	case protoTimestamp:
+		var seconds, nanos int64
+		err := consumeProtoFields(value, func(number protowire.Number, typ protowire.Type, value []byte) error {
+			v, n := protowire.ConsumeVarint(value)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			switch number {
+			case 1:
+				seconds = int64(v)
+			case 2:
+				nanos = int64(int32(v))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano), nil
+	default:
+		return nil, fmt.Errorf("unknown field kind %d", field.Kind)
+	}
+}
+
+// consumeProtoFields calls fn for every field of an encoded message, with
+// the raw bytes of length-delimited values and the encoded varint otherwise.
+func consumeProtoFields(b []byte, fn func(number protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(b) > 0 {
+		number, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(number, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		value := b[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		if err := fn(number, typ, value); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
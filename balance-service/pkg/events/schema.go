@@ -0,0 +1,107 @@
+package events
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// schemaFS holds the JSON Schemas of the events. A breaking change to an event
+// gets a new file with the next version instead of editing the current one.
+//
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// schemaFiles maps every event name to the schema version it is published and
+// validated with.
+var schemaFiles = map[string]string{
+	"BalanceUpdated":     "balance_updated.v1.json",
+	"TransactionCreated": "transaction_created.v1.json",
+}
+
+var ErrNoSchema = errors.New("no schema for event")
+
+// SchemaValidator checks JSON-encoded events against their schema.
+type SchemaValidator struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+func NewSchemaValidator() (*SchemaValidator, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	for _, file := range schemaFiles {
+		data, err := schemaFS.ReadFile(path.Join("schemas", file))
+		if err != nil {
+			return nil, err
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file, err)
+		}
+		if err := compiler.AddResource(file, doc); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file, err)
+		}
+	}
+
+	schemas := map[string]*jsonschema.Schema{}
+	for name, file := range schemaFiles {
+		schema, err := compiler.Compile(file)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file, err)
+		}
+		schemas[name] = schema
+	}
+	return &SchemaValidator{schemas: schemas}, nil
+}
+
+// Validate checks an encoded event against the schema of the event it names.
+func (v *SchemaValidator) Validate(data []byte) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	envelope, _ := doc.(map[string]interface{})
+	name, _ := envelope["name"].(string)
+	schema, ok := v.schemas[name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrNoSchema, name)
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("%s does not match its schema: %w", name, err)
+	}
+	return nil
+}
+
+// ValidateEvent encodes event and checks it against its schema.
+func (v *SchemaValidator) ValidateEvent(event EventInterface) error {
+	data, err := JSONCodec{}.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return v.Validate(data)
+}
+
+var defaultValidator = sync.OnceValues(NewSchemaValidator)
+
+// Validate checks an encoded event with the embedded schemas.
+func Validate(data []byte) error {
+	validator, err := defaultValidator()
+	if err != nil {
+		return err
+	}
+	return validator.Validate(data)
+}
+
+// ValidateEvent checks event with the embedded schemas.
+func ValidateEvent(event EventInterface) error {
+	validator, err := defaultValidator()
+	if err != nil {
+		return err
+	}
+	return validator.ValidateEvent(event)
+}
@@ -1,12 +1,30 @@
 package kafka
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
 	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
+const (
+	// pollTimeout bounds how long a read blocks before the context is checked
+	// again.
+	pollTimeout = 100 * time.Millisecond
+	// redeliveryDelay paces redelivery of a message whose handler failed.
+	redeliveryDelay = time.Second
+)
+
 type Consumer struct {
 	ConfigMap *ckafka.ConfigMap
 	Topics    []string
+	// OnAssigned and OnRevoked, when set, are called as the group hands
+	// partitions to or takes them from this consumer.
+	OnAssigned func(partitions []ckafka.TopicPartition)
+	OnRevoked  func(partitions []ckafka.TopicPartition)
 }
 
 func NewConsumer(configMap *ckafka.ConfigMap, topics []string) *Consumer {
@@ -16,19 +34,79 @@ func NewConsumer(configMap *ckafka.ConfigMap, topics []string) *Consumer {
 	}
 }
 
-func (c *Consumer) Consume(msgChan chan *ckafka.Message) error {
+// Consume reads messages one at a time and commits each offset only after
+// handle has succeeded, so a crash never skips a message that was not
+// applied. When handle fails the partition is rewound to the message, which
+// is delivered again. Auto-commit is turned off on the config map.
+//
+// Consume returns nil once ctx is cancelled, and an error when the consumer
+// cannot be set up, an offset cannot be committed or the client reports a
+// fatal error. The underlying consumer is closed before it returns.
+func (c *Consumer) Consume(ctx context.Context, handle func(msg *ckafka.Message) error) (err error) {
+	if err := c.ConfigMap.SetKey("enable.auto.commit", false); err != nil {
+		return err
+	}
 	consumer, err := ckafka.NewConsumer(c.ConfigMap)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	err = consumer.SubscribeTopics(c.Topics, nil)
-	if err != nil {
-		panic(err)
+	defer func() {
+		if closeErr := consumer.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := consumer.SubscribeTopics(c.Topics, c.rebalanced); err != nil {
+		return err
 	}
 	for {
-		msg, err := consumer.ReadMessage(-1)
-		if err == nil {
-			msgChan <- msg
+		if ctx.Err() != nil {
+			return nil
+		}
+		msg, err := consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			var kafkaErr ckafka.Error
+			if !errors.As(err, &kafkaErr) {
+				return err
+			}
+			if kafkaErr.IsFatal() {
+				return fmt.Errorf("fatal consumer error: %w", kafkaErr)
+			}
+			if kafkaErr.Code() != ckafka.ErrTimedOut {
+				log.Printf("Consumer error: %v", kafkaErr)
+			}
+			continue
+		}
+		if err := handle(msg); err != nil {
+			log.Printf("Failed to handle message from %s, redelivering: %v", msg.TopicPartition, err)
+			if err := consumer.Seek(msg.TopicPartition, 0); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(redeliveryDelay):
+			}
+			continue
+		}
+		if _, err := consumer.CommitMessage(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// rebalanced reports assignment changes. It does not assign partitions
+// itself, which leaves that to the client's default handling.
+func (c *Consumer) rebalanced(_ *ckafka.Consumer, event ckafka.Event) error {
+	switch e := event.(type) {
+	case ckafka.AssignedPartitions:
+		if c.OnAssigned != nil {
+			c.OnAssigned(e.Partitions)
+		}
+	case ckafka.RevokedPartitions:
+		if c.OnRevoked != nil {
+			c.OnRevoked(e.Partitions)
 		}
 	}
+	return nil
 }
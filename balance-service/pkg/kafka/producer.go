@@ -1,38 +1,175 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
+// closeFlushTimeout bounds how long Close waits for queued messages.
+const closeFlushTimeout = 15 * time.Second
+
+var ErrProducerClosed = errors.New("producer is closed")
+
+// Producer publishes through one long-lived client. Publish waits for the
+// delivery report of its message; PublishAsync returns once the message is
+// queued and reports the delivery to the handler set with OnDelivery.
 type Producer struct {
 	ConfigMap *ckafka.ConfigMap
+
+	producer   *ckafka.Producer
+	events     sync.WaitGroup
+	mu         sync.RWMutex
+	closed     bool
+	onDelivery func(msg *ckafka.Message, err error)
+}
+
+func NewKafkaProducer(configMap *ckafka.ConfigMap) (*Producer, error) {
+	producer, err := ckafka.NewProducer(configMap)
+	if err != nil {
+		return nil, err
+	}
+	p := &Producer{
+		ConfigMap: configMap,
+		producer:  producer,
+	}
+	p.events.Add(1)
+	go p.handleEvents()
+	return p, nil
 }
 
-func NewKafkaProducer(configMap *ckafka.ConfigMap) *Producer {
-	return &Producer{ConfigMap: configMap}
+// OnDelivery sets the handler receiving the outcome of every message
+// published with PublishAsync. It runs on the producer's event goroutine;
+// without one, failed deliveries are logged.
+func (p *Producer) OnDelivery(handler func(msg *ckafka.Message, err error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onDelivery = handler
 }
 
+// Publish sends msg encoded as JSON and waits for its delivery report.
 func (p *Producer) Publish(msg interface{}, key []byte, topic string) error {
-	producer, err := ckafka.NewProducer(p.ConfigMap)
+	message, err := newJSONMessage(msg, key, topic)
 	if err != nil {
 		return err
 	}
+	return p.PublishMessage(message)
+}
 
-	msgJson, err := json.Marshal(msg)
+// PublishAsync queues msg encoded as JSON without waiting for delivery.
+func (p *Producer) PublishAsync(msg interface{}, key []byte, topic string) error {
+	message, err := newJSONMessage(msg, key, topic)
 	if err != nil {
 		return err
 	}
+	return p.produce(message, nil)
+}
 
-	message := &ckafka.Message{
-		TopicPartition: ckafka.TopicPartition{Topic: &topic, Partition: ckafka.PartitionAny},
-		Value:          msgJson,
-		Key:            key,
+// PublishMessage produces a prepared message and waits for its delivery
+// report.
+func (p *Producer) PublishMessage(message *ckafka.Message) error {
+	return p.PublishMessageContext(context.Background(), message)
+}
+
+// PublishMessageContext produces a prepared message and waits for its
+// delivery report or for ctx to be done, whichever comes first. A message
+// given up on may still be delivered.
+func (p *Producer) PublishMessageContext(ctx context.Context, message *ckafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deliveries := make(chan ckafka.Event, 1)
+	if err := p.produce(message, deliveries); err != nil {
+		return err
+	}
+	select {
+	case report := <-deliveries:
+		switch report := report.(type) {
+		case *ckafka.Message:
+			return report.TopicPartition.Error
+		case ckafka.Error:
+			return report
+		default:
+			return fmt.Errorf("unexpected delivery event %v", report)
+		}
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	err = producer.Produce(message, nil)
+}
+
+// Flush waits up to timeout for queued messages to be delivered and returns
+// how many are still outstanding.
+func (p *Producer) Flush(timeout time.Duration) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return 0
+	}
+	return p.producer.Flush(int(timeout.Milliseconds()))
+}
+
+// Close flushes queued messages and releases the client. Publishing after
+// Close returns ErrProducerClosed.
+func (p *Producer) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	if remaining := p.producer.Flush(int(closeFlushTimeout.Milliseconds())); remaining > 0 {
+		log.Printf("Producer closed with %d undelivered message(s)", remaining)
+	}
+	p.producer.Close()
+	p.events.Wait()
+}
+
+func (p *Producer) produce(message *ckafka.Message, deliveries chan ckafka.Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+	return p.producer.Produce(message, deliveries)
+}
+
+// handleEvents reports deliveries of asynchronously published messages and
+// client errors until the client is closed.
+func (p *Producer) handleEvents() {
+	defer p.events.Done()
+	for event := range p.producer.Events() {
+		switch e := event.(type) {
+		case *ckafka.Message:
+			p.mu.RLock()
+			onDelivery := p.onDelivery
+			p.mu.RUnlock()
+			if onDelivery != nil {
+				onDelivery(e, e.TopicPartition.Error)
+			} else if e.TopicPartition.Error != nil {
+				log.Printf("Failed to deliver message to %s: %v", e.TopicPartition, e.TopicPartition.Error)
+			}
+		case ckafka.Error:
+			log.Printf("Producer error: %v", e)
+		}
+	}
+}
+
+func newJSONMessage(msg interface{}, key []byte, topic string) (*ckafka.Message, error) {
+	msgJson, err := json.Marshal(msg)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return nil
+	return &ckafka.Message{
+		TopicPartition: ckafka.TopicPartition{Topic: &topic, Partition: ckafka.PartitionAny},
+		Value:          msgJson,
+		Key:            key,
+	}, nil
 }
@@ -1,10 +1,13 @@
 package kafka
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestProducerPublish(t *testing.T) {
@@ -24,7 +27,55 @@ func TestProducerPublish(t *testing.T) {
 	configMap := ckafka.ConfigMap{
 		"test.mock.num.brokers": 3,
 	}
-	producer := NewKafkaProducer(&configMap)
-	err := producer.Publish(expectedOutput, []byte("1"), "test")
+	producer, err := NewKafkaProducer(&configMap)
+	require.Nil(t, err)
+	defer producer.Close()
+	err = producer.Publish(expectedOutput, []byte("1"), "test")
 	assert.Nil(t, err)
 }
+
+func TestProducerPublishReturnsEncodingErrors(t *testing.T) {
+	producer, err := NewKafkaProducer(&ckafka.ConfigMap{"test.mock.num.brokers": 1})
+	require.Nil(t, err)
+	defer producer.Close()
+
+	err = producer.Publish(make(chan int), nil, "test")
+
+	assert.NotNil(t, err)
+}
+
+func TestProducerPublishAsyncReportsDelivery(t *testing.T) {
+	producer, err := NewKafkaProducer(&ckafka.ConfigMap{"test.mock.num.brokers": 1})
+	require.Nil(t, err)
+	defer producer.Close()
+
+	var mu sync.Mutex
+	delivered := []string{}
+	producer.OnDelivery(func(msg *ckafka.Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Nil(t, err)
+		delivered = append(delivered, string(msg.Value))
+	})
+
+	assert.Nil(t, producer.PublishAsync("a", nil, "test"))
+	assert.Nil(t, producer.PublishAsync("b", nil, "test"))
+	assert.Equal(t, 0, producer.Flush(10*time.Second))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(delivered) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProducerClose(t *testing.T) {
+	producer, err := NewKafkaProducer(&ckafka.ConfigMap{"test.mock.num.brokers": 1})
+	require.Nil(t, err)
+
+	producer.Close()
+	producer.Close()
+
+	assert.ErrorIs(t, producer.Publish("a", nil, "test"), ErrProducerClosed)
+	assert.Equal(t, 0, producer.Flush(time.Second))
+}
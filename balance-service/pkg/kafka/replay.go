@@ -0,0 +1,151 @@
+package kafka
+
+import (
+	"balance/pkg/messaging"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+const replayTimeoutMs = 10000
+
+// Replay reads the consumer's topics from the given position up to the end
+// offsets observed when it starts, calling handle for every message, and
+// returns those end offsets once all partitions are caught up. It assigns
+// partitions directly and never commits, so the group in the config map is
+// only used to identify the replay and live consumers are unaffected. An
+// error from handle or a cancelled ctx stops the replay.
+func (c *Consumer) Replay(ctx context.Context, from messaging.ReplayPosition, handle func(msg *ckafka.Message) error) (messaging.Offsets, error) {
+	consumer, err := ckafka.NewConsumer(c.ConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	defer consumer.Close()
+
+	partitions, ends, err := c.replayPartitions(consumer, from)
+	if err != nil {
+		return nil, err
+	}
+	if len(partitions) == 0 {
+		return ends, nil
+	}
+	if err := consumer.Assign(partitions); err != nil {
+		return nil, err
+	}
+
+	pending := map[messaging.TopicPartition]bool{}
+	for _, partition := range partitions {
+		pending[messaging.TopicPartition{Topic: *partition.Topic, Partition: partition.Partition}] = true
+	}
+	lastProgress := time.Now()
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			var kafkaErr ckafka.Error
+			if !errors.As(err, &kafkaErr) || kafkaErr.Code() != ckafka.ErrTimedOut {
+				return nil, err
+			}
+			if time.Since(lastProgress) > replayTimeoutMs*time.Millisecond {
+				return nil, fmt.Errorf("replay stalled with %d partition(s) not caught up", len(pending))
+			}
+			continue
+		}
+		lastProgress = time.Now()
+		key := messaging.TopicPartition{Topic: *msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition}
+		if !pending[key] || int64(msg.TopicPartition.Offset) >= ends[key] {
+			continue
+		}
+		if err := handle(msg); err != nil {
+			return nil, fmt.Errorf("replaying %s: %w", msg.TopicPartition, err)
+		}
+		if int64(msg.TopicPartition.Offset)+1 >= ends[key] {
+			delete(pending, key)
+		}
+	}
+	return ends, nil
+}
+
+// Replayer replays topics with a new Consumer per replay, handing
+// broker-agnostic messages to the handler.
+type Replayer struct {
+	ConfigMap *ckafka.ConfigMap
+}
+
+func NewReplayer(configMap *ckafka.ConfigMap) *Replayer {
+	return &Replayer{ConfigMap: configMap}
+}
+
+// Replay implements messaging.Replayer. See Consumer.Replay.
+func (r *Replayer) Replay(ctx context.Context, topics []string, from messaging.ReplayPosition, handle messaging.Handler) (messaging.Offsets, error) {
+	consumer := NewConsumer(r.ConfigMap, topics)
+	return consumer.Replay(ctx, from, func(msg *ckafka.Message) error {
+		return handle(ctx, ToMessage(msg))
+	})
+}
+
+// replayPartitions resolves the start offset of every partition and the end
+// offset of every partition. Partitions with nothing to replay are left out
+// of the assignment.
+func (c *Consumer) replayPartitions(consumer *ckafka.Consumer, from messaging.ReplayPosition) ([]ckafka.TopicPartition, messaging.Offsets, error) {
+	partitions := []ckafka.TopicPartition{}
+	ends := messaging.Offsets{}
+	for _, topic := range c.Topics {
+		topic := topic
+		metadata, err := consumer.GetMetadata(&topic, false, replayTimeoutMs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, partition := range metadata.Topics[topic].Partitions {
+			low, high, err := consumer.QueryWatermarkOffsets(topic, partition.ID, replayTimeoutMs)
+			if err != nil {
+				return nil, nil, err
+			}
+			ends[messaging.TopicPartition{Topic: topic, Partition: partition.ID}] = high
+			start, err := replayStart(consumer, topic, partition.ID, from, low)
+			if err != nil {
+				return nil, nil, err
+			}
+			if start < 0 || start >= high {
+				continue
+			}
+			partitions = append(partitions, ckafka.TopicPartition{
+				Topic:     &topic,
+				Partition: partition.ID,
+				Offset:    ckafka.Offset(start),
+			})
+		}
+	}
+	return partitions, ends, nil
+}
+
+// replayStart returns the first offset to read, or -1 when no message at or
+// after the timestamp exists.
+func replayStart(consumer *ckafka.Consumer, topic string, partition int32, from messaging.ReplayPosition, low int64) (int64, error) {
+	if offset, ok := from.Offsets[messaging.TopicPartition{Topic: topic, Partition: partition}]; ok {
+		return max(offset, low), nil
+	}
+	if from.Timestamp.IsZero() {
+		if from.Offset < low {
+			return low, nil
+		}
+		return from.Offset, nil
+	}
+	offsets, err := consumer.OffsetsForTimes([]ckafka.TopicPartition{{
+		Topic:     &topic,
+		Partition: partition,
+		Offset:    ckafka.Offset(from.Timestamp.UnixMilli()),
+	}}, replayTimeoutMs)
+	if err != nil {
+		return 0, err
+	}
+	if len(offsets) == 0 || offsets[0].Offset < 0 {
+		return -1, nil
+	}
+	return int64(offsets[0].Offset), nil
+}
@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"balance/pkg/messaging"
+	"context"
+	"errors"
+	"testing"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func produceTestMessages(t *testing.T, bootstrapServers string, topic string, values ...string) {
+	producer, err := ckafka.NewProducer(&ckafka.ConfigMap{
+		"bootstrap.servers":   bootstrapServers,
+		"go.delivery.reports": false,
+	})
+	require.Nil(t, err)
+	defer producer.Close()
+
+	for _, value := range values {
+		err := producer.Produce(&ckafka.Message{
+			TopicPartition: ckafka.TopicPartition{Topic: &topic, Partition: 0},
+			Value:          []byte(value),
+		}, nil)
+		require.Nil(t, err)
+	}
+	require.Equal(t, 0, producer.Flush(10000))
+}
+
+func newReplayTestConsumer(t *testing.T, values ...string) *Consumer {
+	cluster, err := ckafka.NewMockCluster(1)
+	require.Nil(t, err)
+	t.Cleanup(cluster.Close)
+
+	produceTestMessages(t, cluster.BootstrapServers(), "balances", values...)
+	return NewConsumer(&ckafka.ConfigMap{
+		"bootstrap.servers": cluster.BootstrapServers(),
+		"group.id":          "balance-service-rebuild-test",
+	}, []string{"balances"})
+}
+
+func TestConsumerReplay(t *testing.T) {
+	t.Run("reads every message from the beginning and returns", func(t *testing.T) {
+		consumer := newReplayTestConsumer(t, "a", "b", "c")
+
+		var got []string
+		_, err := consumer.Replay(context.Background(), messaging.ReplayPosition{}, func(msg *ckafka.Message) error {
+			got = append(got, string(msg.Value))
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, got)
+	})
+
+	t.Run("starts at the given offset", func(t *testing.T) {
+		consumer := newReplayTestConsumer(t, "a", "b", "c")
+
+		var got []string
+		_, err := consumer.Replay(context.Background(), messaging.ReplayPosition{Offset: 1}, func(msg *ckafka.Message) error {
+			got = append(got, string(msg.Value))
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"b", "c"}, got)
+	})
+
+	t.Run("stops on handler error", func(t *testing.T) {
+		consumer := newReplayTestConsumer(t, "a", "b")
+		handlerErr := errors.New("boom")
+
+		calls := 0
+		_, err := consumer.Replay(context.Background(), messaging.ReplayPosition{}, func(msg *ckafka.Message) error {
+			calls++
+			return handlerErr
+		})
+
+		assert.ErrorIs(t, err, handlerErr)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("returns the end offsets to pick up from", func(t *testing.T) {
+		consumer := newReplayTestConsumer(t, "a", "b")
+
+		ends, err := consumer.Replay(context.Background(), messaging.ReplayPosition{}, func(msg *ckafka.Message) error {
+			return nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, int64(2), ends[messaging.TopicPartition{Topic: "balances", Partition: 0}])
+
+		// Nothing is left past the ends
+		calls := 0
+		_, err = consumer.Replay(context.Background(), messaging.ReplayPosition{Offsets: ends}, func(msg *ckafka.Message) error {
+			calls++
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, calls)
+	})
+}
@@ -0,0 +1,120 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMessage(topic string) *Message {
+	return &Message{
+		Topic:     topic,
+		Partition: 2,
+		Offset:    41,
+		Key:       []byte("account1"),
+		Value:     []byte(`{"Name":"BalanceUpdated"}`),
+		Headers:   map[string]string{"trace": "abc"},
+	}
+}
+
+func TestNewDeadLetterMessage(t *testing.T) {
+	failedAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+
+	dlq := NewDeadLetterMessage(newTestMessage("balances"), errors.New("boom"), 5, failedAt)
+
+	assert.Equal(t, "balances.dlq", dlq.Topic)
+	assert.Equal(t, []byte("account1"), dlq.Key)
+	assert.Equal(t, []byte(`{"Name":"BalanceUpdated"}`), dlq.Value)
+	assert.Equal(t, "abc", dlq.Headers["trace"])
+	assert.Equal(t, "balances", dlq.Headers[HeaderOriginalTopic])
+	assert.Equal(t, "2", dlq.Headers[HeaderOriginalPartition])
+	assert.Equal(t, "41", dlq.Headers[HeaderOriginalOffset])
+	assert.Equal(t, "boom", dlq.Headers[HeaderError])
+	assert.Equal(t, "5", dlq.Headers[HeaderAttempts])
+	assert.Equal(t, "2025-03-03T10:00:00Z", dlq.Headers[HeaderFailedAt])
+}
+
+func TestNewRedriveMessage(t *testing.T) {
+	t.Run("restores topic and strips dead-letter headers", func(t *testing.T) {
+		dlq := NewDeadLetterMessage(newTestMessage("balances"), errors.New("boom"), 5, time.Now())
+
+		redriven, err := NewRedriveMessage(dlq)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "balances", redriven.Topic)
+		assert.Equal(t, map[string]string{"trace": "abc"}, redriven.Headers)
+	})
+
+	t.Run("rejects messages without an original topic", func(t *testing.T) {
+		_, err := NewRedriveMessage(newTestMessage("balances.dlq"))
+
+		assert.ErrorIs(t, err, ErrNotDeadLettered)
+	})
+}
+
+func TestDeadLetterQueueWithRetry(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	queue := NewDeadLetterQueue(broker)
+	policy := RetryPolicy{MaxAttempts: 3, Sleep: func(context.Context, time.Duration) error { return nil }}
+
+	calls := 0
+	handle := queue.WithRetry(policy, func(ctx context.Context, msg *Message) error {
+		calls++
+		return errors.New("boom")
+	})
+
+	assert.Nil(t, handle(context.Background(), newTestMessage("balances")))
+	assert.Equal(t, 3, calls)
+
+	parked := broker.Messages("balances.dlq")
+	require.Len(t, parked, 1)
+	assert.Equal(t, "3", parked[0].Headers[HeaderAttempts])
+	assert.Equal(t, "boom", parked[0].Headers[HeaderError])
+}
+
+func TestDeadLetterQueueWithRetryStopsWithContext(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	queue := NewDeadLetterQueue(broker)
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	handle := queue.WithRetry(policy, func(ctx context.Context, msg *Message) error {
+		calls++
+		cancel()
+		return errors.New("boom")
+	})
+
+	assert.ErrorIs(t, handle(ctx, newTestMessage("balances")), context.Canceled)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, broker.Messages("balances.dlq"))
+}
+
+func TestDeadLetterQueueWithRetryQuarantinesInvalidMessages(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	queue := NewDeadLetterQueue(broker)
+	policy := RetryPolicy{MaxAttempts: 3, Sleep: func(context.Context, time.Duration) error { return nil }}
+
+	calls := 0
+	handle := queue.WithRetry(policy, func(ctx context.Context, msg *Message) error {
+		calls++
+		return Invalid(errors.New("amount must be positive"))
+	})
+
+	assert.Nil(t, handle(context.Background(), newTestMessage("balances")))
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, broker.Messages("balances.dlq"))
+
+	quarantined := broker.Messages("balances.quarantine")
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, "balances", quarantined[0].Headers[HeaderOriginalTopic])
+	assert.Equal(t, "amount must be positive", quarantined[0].Headers[HeaderError])
+
+	redriven, err := NewRedriveMessage(quarantined[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "balances", redriven.Topic)
+}
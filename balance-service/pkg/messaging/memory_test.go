@@ -0,0 +1,227 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageID(t *testing.T) {
+	msg := &Message{Topic: "balances", Partition: 2, Offset: 41}
+
+	assert.Equal(t, "balances/2/41", MessageID(msg))
+}
+
+func publishTestMessages(t *testing.T, broker *MemoryBroker, topic string, key string, values ...string) {
+	for _, value := range values {
+		msg := &Message{Topic: topic, Value: []byte(value)}
+		if key != "" {
+			msg.Key = []byte(key)
+		}
+		require.Nil(t, broker.Publish(context.Background(), msg))
+	}
+}
+
+// consumeN subscribes until n messages were handled and returns their values.
+func consumeN(t *testing.T, subscriber Subscriber, n int) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []string
+	err := subscriber.Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+		got = append(got, string(msg.Value))
+		if len(got) == n {
+			cancel()
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	return got
+}
+
+func TestMemoryBrokerPublish(t *testing.T) {
+	t.Run("keeps messages with the same key on one partition in order", func(t *testing.T) {
+		broker := NewMemoryBroker(4)
+		publishTestMessages(t, broker, "balances", "account1", "a", "b", "c")
+
+		messages := broker.Messages("balances")
+
+		require.Len(t, messages, 3)
+		for i, msg := range messages {
+			assert.Equal(t, messages[0].Partition, msg.Partition)
+			assert.Equal(t, int64(i), msg.Offset)
+			assert.False(t, msg.Timestamp.IsZero())
+		}
+	})
+
+	t.Run("spreads messages without a key over the partitions", func(t *testing.T) {
+		broker := NewMemoryBroker(2)
+		publishTestMessages(t, broker, "balances", "", "a", "b")
+
+		messages := broker.Messages("balances")
+
+		require.Len(t, messages, 2)
+		assert.NotEqual(t, messages[0].Partition, messages[1].Partition)
+	})
+}
+
+func TestMemorySubscriberSubscribe(t *testing.T) {
+	t.Run("commits handled messages and resumes from the committed offset", func(t *testing.T) {
+		broker := NewMemoryBroker(1)
+		publishTestMessages(t, broker, "balances", "account1", "a", "b")
+
+		assert.Equal(t, []string{"a", "b"}, consumeN(t, broker.Subscriber("balance-service", "balances"), 2))
+		assert.Equal(t, int64(2), broker.Committed("balance-service", "balances", 0))
+
+		publishTestMessages(t, broker, "balances", "account1", "c")
+		assert.Equal(t, []string{"c"}, consumeN(t, broker.Subscriber("balance-service", "balances"), 1))
+	})
+
+	t.Run("keeps separate offsets per group", func(t *testing.T) {
+		broker := NewMemoryBroker(1)
+		publishTestMessages(t, broker, "balances", "account1", "a", "b")
+
+		consumeN(t, broker.Subscriber("balance-service", "balances"), 2)
+
+		assert.Equal(t, []string{"a", "b"}, consumeN(t, broker.Subscriber("audit", "balances"), 2))
+	})
+
+	t.Run("redelivers a message whose handler failed", func(t *testing.T) {
+		broker := NewMemoryBroker(1)
+		broker.RedeliveryDelay = time.Millisecond
+		publishTestMessages(t, broker, "balances", "account1", "a", "b")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var got []string
+		err := broker.Subscriber("balance-service", "balances").Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+			got = append(got, string(msg.Value))
+			if len(got) == 1 {
+				return errors.New("database unavailable")
+			}
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "a", "b"}, got)
+	})
+
+	t.Run("keeps handling other partitions while one keeps failing", func(t *testing.T) {
+		broker := NewMemoryBroker(2)
+		broker.RedeliveryDelay = time.Millisecond
+		// Unkeyed messages alternate between the partitions
+		publishTestMessages(t, broker, "balances", "", "a", "b", "c", "d")
+		failing := broker.Messages("balances")[0].Partition
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var got []string
+		err := broker.Subscriber("balance-service", "balances").Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+			if msg.Partition == failing {
+				return errors.New("database unavailable")
+			}
+			got = append(got, string(msg.Value))
+			if len(got) == 2 {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Len(t, got, 2)
+		assert.Equal(t, int64(0), broker.Committed("balance-service", "balances", failing))
+	})
+
+	t.Run("delivers messages published while subscribed", func(t *testing.T) {
+		broker := NewMemoryBroker(1)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			publishTestMessages(t, broker, "transactions", "account1", "a")
+		}()
+
+		assert.Equal(t, []string{"a"}, consumeN(t, broker.Subscriber("balance-service", "transactions"), 1))
+	})
+
+	t.Run("shares partitions among the members of a group", func(t *testing.T) {
+		broker := NewMemoryBroker(2)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		partitions := []map[int32]bool{{}, {}}
+		handled := 0
+		var wg sync.WaitGroup
+		for member := range partitions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				broker.Subscriber("balance-service", "balances").Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+					mu.Lock()
+					defer mu.Unlock()
+					partitions[member][msg.Partition] = true
+					handled++
+					if handled == 4 {
+						cancel()
+					}
+					return nil
+				})
+			}()
+		}
+		require.Eventually(t, func() bool {
+			broker.mu.Lock()
+			defer broker.mu.Unlock()
+			group, ok := broker.groups["balance-service"]
+			return ok && len(group.members) == 2
+		}, time.Second, time.Millisecond)
+		publishTestMessages(t, broker, "balances", "", "a", "b", "c", "d")
+		wg.Wait()
+
+		assert.Equal(t, int64(2), broker.Committed("balance-service", "balances", 0))
+		assert.Equal(t, int64(2), broker.Committed("balance-service", "balances", 1))
+		assert.Len(t, partitions[0], 1)
+		assert.Len(t, partitions[1], 1)
+		assert.NotEqual(t, partitions[0], partitions[1])
+	})
+}
+
+func TestMemoryBrokerReplay(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(1)
+	publishTestMessages(t, broker, "balances", "account1", "a", "b")
+	require.Nil(t, broker.Publish(ctx, &Message{Topic: "balances", Key: []byte("account1"), Value: []byte("c"), Timestamp: time.Now().Add(time.Hour)}))
+
+	replay := func(from ReplayPosition) ([]string, Offsets) {
+		var got []string
+		ends, err := broker.Replay(ctx, []string{"balances"}, from, func(ctx context.Context, msg *Message) error {
+			got = append(got, string(msg.Value))
+			return nil
+		})
+		require.Nil(t, err)
+		return got, ends
+	}
+
+	got, ends := replay(ReplayPosition{})
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+	assert.Equal(t, Offsets{{Topic: "balances", Partition: 0}: 3}, ends)
+
+	got, _ = replay(ReplayPosition{Offset: 1})
+	assert.Equal(t, []string{"b", "c"}, got)
+	got, _ = replay(ReplayPosition{Timestamp: time.Now().Add(time.Minute)})
+	assert.Equal(t, []string{"c"}, got)
+
+	// A later replay picks up where an earlier one stopped
+	publishTestMessages(t, broker, "balances", "account1", "d")
+	got, _ = replay(ReplayPosition{Offsets: ends})
+	assert.Equal(t, []string{"d"}, got)
+
+	// Replaying does not commit anything for live groups
+	assert.Equal(t, int64(0), broker.Committed("balance-service", "balances", 0))
+}
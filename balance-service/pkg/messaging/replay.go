@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"context"
+	"time"
+)
+
+// TopicPartition identifies one partition of a topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// Offsets holds an offset per partition.
+type Offsets map[TopicPartition]int64
+
+// ReplayPosition selects where a replay starts on every partition. Offsets
+// wins for the partitions it holds, e.g. the ends returned by an earlier
+// replay; otherwise a non-zero Timestamp starts at the first message at or
+// after it, and Offset is used when neither is set. The zero value starts at
+// the beginning.
+type ReplayPosition struct {
+	Offset    int64
+	Timestamp time.Time
+	Offsets   Offsets
+}
+
+// Replayer reads topics from a position up to the end offsets observed when
+// it starts, calling handle for every message in order per partition, and
+// returns those end offsets so a later replay can pick up where it stopped.
+// It neither joins a consumer group nor commits, so live consumers are
+// unaffected. An error from handle or a cancelled ctx stops the replay.
+type Replayer interface {
+	Replay(ctx context.Context, topics []string, from ReplayPosition, handle Handler) (Offsets, error)
+}
@@ -0,0 +1,60 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type itemAdder interface {
+	Add(id string) error
+}
+
+func TestRepositoryName(t *testing.T) {
+	pkgPath := reflect.TypeFor[itemAdder]().PkgPath()
+
+	assert.Equal(t, pkgPath+".itemAdder", RepositoryName[itemAdder]())
+	assert.Equal(t, "*"+pkgPath+".testRepository", RepositoryName[*testRepository]())
+	assert.Equal(t, "string", RepositoryName[string]())
+}
+
+func TestRegisterAndGet(t *testing.T) {
+	u := newTestUow(t)
+	Register(u, func(tx *sql.Tx) itemAdder {
+		return &testRepository{tx: tx}
+	})
+
+	err := u.Do(context.Background(), func(ctx context.Context) error {
+		items, err := Get[itemAdder](ctx, u)
+		if err != nil {
+			return err
+		}
+		return items.Add("a")
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countItems(t, u))
+}
+
+func TestGet(t *testing.T) {
+	u := newTestUow(t)
+	u.Register(RepositoryName[itemAdder](), func(tx *sql.Tx) interface{} {
+		return "not a repository"
+	})
+
+	err := u.Do(context.Background(), func(ctx context.Context) error {
+		_, err := Get[itemAdder](ctx, u)
+		assert.ErrorIs(t, err, ErrRepositoryType)
+
+		_, err = Get[*sql.DB](ctx, u)
+		assert.ErrorIs(t, err, ErrRepositoryNotRegistered)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	_, err = Get[itemAdder](context.Background(), u)
+	assert.ErrorIs(t, err, ErrNoTransaction)
+}
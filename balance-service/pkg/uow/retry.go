@@ -0,0 +1,157 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+)
+
+// Driver error codes that mean the transaction lost a race with another one
+// and can be run again from the start.
+const (
+	mysqlLockWaitTimeout  = 1205
+	mysqlDeadlock         = 1213
+	sqliteBusy            = 5
+	sqliteLocked          = 6
+	postgresSerialization = "40001"
+	postgresDeadlock      = "40P01"
+)
+
+// RetryPolicy re-runs a unit of work that failed with a retryable error,
+// waiting a jittered, exponentially growing backoff between attempts.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Retryable classifies errors; nil uses IsRetryable.
+	Retryable func(err error) bool
+	// OnRetry is called before every new attempt, e.g. to count retries.
+	OnRetry func(attempt int, err error)
+	// Sleep waits between attempts, returning early with an error when ctx is
+	// done; nil uses a timer.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+}
+
+// Backoff returns the wait after the given failed attempt, counted from 1:
+// half of the exponential backoff plus a random share of the other half, so
+// transactions that collided do not collide again.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			backoff = float64(p.MaxBackoff)
+			break
+		}
+	}
+	half := time.Duration(backoff / 2)
+	if half <= 0 {
+		return half
+	}
+	return half + rand.N(half+1)
+}
+
+// RetryError is returned when a unit of work still failed with a retryable
+// error after the last attempt.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err is a MySQL deadlock or lock wait timeout,
+// a PostgreSQL deadlock or serialization failure, or a SQLite busy or locked
+// database.
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == postgresDeadlock || pgErr.Code == postgresSerialization
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Extended result codes keep the primary code in the low byte
+		code := sqliteErr.Code() & 0xff
+		return code == sqliteBusy || code == sqliteLocked
+	}
+	return false
+}
+
+type attemptKey struct{}
+
+// Attempt returns which attempt, counted from 1, the unit of work running
+// ctx is on.
+func Attempt(ctx context.Context) int {
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+	if !ok {
+		return 0
+	}
+	return attempt
+}
+
+// Run calls fn until it succeeds, fails with an error that is not retryable
+// or the attempts are exhausted. The context passed to fn carries the attempt
+// number.
+func (p RetryPolicy) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(context.WithValue(ctx, attemptKey{}, attempt))
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt+1, err)
+		}
+		if sleep(ctx, p.Backoff(attempt)) != nil {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
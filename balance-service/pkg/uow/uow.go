@@ -5,93 +5,135 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
 )
 
 type RepositoryFactory func(tx *sql.Tx) interface{}
 
+var (
+	ErrNoTransaction           = errors.New("no transaction in context")
+	ErrRepositoryNotRegistered = errors.New("repository not registered")
+)
+
 type UowInterface interface {
 	Register(name string, fc RepositoryFactory)
 	GetRepository(ctx context.Context, name string) (interface{}, error)
-	Do(ctx context.Context, fn func(uow *Uow) error) error
-	CommitOrRollback() error
-	Rollback() error
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
 	UnRegister(name string)
 }
 
+// Uow is shared by all requests. Every Do call runs in its own transaction,
+// carried in the context it passes to fn, and GetRepository binds
+// repositories to the transaction of the context it is given. A Do call
+// inside another one runs in a savepoint of the outer transaction.
 type Uow struct {
 	Db           *sql.DB
-	Tx           *sql.Tx
+	mu           sync.RWMutex
 	Repositories map[string]RepositoryFactory
+	// RetryPolicy re-runs a whole Do call that lost a race with another
+	// transaction, such as a deadlock.
+	RetryPolicy RetryPolicy
+}
+
+type scopeKey struct{}
+
+// scope is the transaction of a Do call and how deeply it is nested.
+type scope struct {
+	tx    *sql.Tx
+	depth int
 }
 
 func NewUow(ctx context.Context, db *sql.DB) *Uow {
 	return &Uow{
 		Db:           db,
 		Repositories: make(map[string]RepositoryFactory),
+		RetryPolicy:  NewRetryPolicy(),
+	}
+}
+
+// TxFromContext returns the transaction of the unit of work running ctx.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return nil, false
 	}
+	return s.tx, true
 }
 
 func (u *Uow) Register(name string, fc RepositoryFactory) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	u.Repositories[name] = fc
 }
 
 func (u *Uow) UnRegister(name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	delete(u.Repositories, name)
 }
 
+// GetRepository returns the named repository bound to the transaction of
+// ctx, which must come from Do.
 func (u *Uow) GetRepository(ctx context.Context, name string) (interface{}, error) {
-	if u.Tx == nil {
-		tx, err := u.Db.BeginTx(ctx, nil)
-		if err != nil {
-			return nil, err
-		}
-		u.Tx = tx
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTransaction
 	}
-	repo := u.Repositories[name](u.Tx)
-	return repo, nil
+	u.mu.RLock()
+	factory, ok := u.Repositories[name]
+	u.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRepositoryNotRegistered, name)
+	}
+	return factory(tx), nil
 }
 
-func (u *Uow) Do(ctx context.Context, fn func(Uow *Uow) error) error {
-	if u.Tx != nil {
-		return fmt.Errorf("transaction already started")
+// Do runs fn in a new transaction, committing it when fn succeeds and
+// rolling it back otherwise. Repositories must be resolved with the context
+// passed to fn.
+//
+// Called with the context of another Do, it runs fn in a savepoint instead,
+// so a failure only undoes the work of fn and the outer unit of work decides
+// whether to go on. Use cases can thus be composed into larger ones.
+//
+// Retryable failures, such as deadlocks, run fn again from the start in a new
+// transaction, so fn must not keep state across attempts. Only the outermost
+// Do retries, since the failure aborts the whole transaction.
+func (u *Uow) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if outer, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		return doInSavepoint(ctx, outer, fn)
 	}
-	tx, err := u.Db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	u.Tx = tx
-	err = fn(u)
-	if err != nil {
-		errRb := u.Rollback()
-		if errRb != nil {
-			return errors.New(fmt.Sprintf("original error: %s, rollback error: %s", err.Error(), errRb.Error()))
+	return u.RetryPolicy.Run(ctx, func(ctx context.Context) error {
+		tx, err := u.Db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
 		}
-		return err
-	}
-	return u.CommitOrRollback()
+		err = fn(context.WithValue(ctx, scopeKey{}, &scope{tx: tx}))
+		if err != nil {
+			errRb := tx.Rollback()
+			if errRb != nil {
+				return fmt.Errorf("original error: %w, rollback error: %w", err, errRb)
+			}
+			return err
+		}
+		return tx.Commit()
+	})
 }
 
-func (u *Uow) Rollback() error {
-	if u.Tx == nil {
-		return errors.New("no transaction to rollback")
-	}
-	err := u.Tx.Rollback()
-	if err != nil {
+func doInSavepoint(ctx context.Context, outer *scope, fn func(ctx context.Context) error) error {
+	inner := &scope{tx: outer.tx, depth: outer.depth + 1}
+	savepoint := fmt.Sprintf("uow_%d", inner.depth)
+	if _, err := inner.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
 		return err
 	}
-	u.Tx = nil
-	return nil
-}
-
-func (u *Uow) CommitOrRollback() error {
-	err := u.Tx.Commit()
+	err := fn(context.WithValue(ctx, scopeKey{}, inner))
 	if err != nil {
-		errRb := u.Rollback()
+		_, errRb := inner.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
 		if errRb != nil {
-			return errors.New(fmt.Sprintf("original error: %s, rollback error: %s", err.Error(), errRb.Error()))
+			return fmt.Errorf("original error: %w, rollback error: %w", err, errRb)
 		}
 		return err
 	}
-	u.Tx = nil
-	return nil
+	_, err = inner.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
 }
@@ -0,0 +1,222 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+type testRepository struct {
+	tx *sql.Tx
+}
+
+func (r *testRepository) Add(id string) error {
+	_, err := r.tx.Exec(`INSERT INTO items (id) VALUES (?)`, id)
+	return err
+}
+
+func newTestUow(t *testing.T) *Uow {
+	path := filepath.Join(t.TempDir(), "uow.db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE items (id TEXT PRIMARY KEY)`)
+	require.Nil(t, err)
+
+	u := NewUow(context.Background(), db)
+	u.Register("ItemRepository", func(tx *sql.Tx) interface{} {
+		return &testRepository{tx: tx}
+	})
+	return u
+}
+
+func countItems(t *testing.T, u *Uow) int {
+	var count int
+	require.Nil(t, u.Db.QueryRow(`SELECT COUNT(*) FROM items`).Scan(&count))
+	return count
+}
+
+func addItem(ctx context.Context, u *Uow, id string) error {
+	repository, err := u.GetRepository(ctx, "ItemRepository")
+	if err != nil {
+		return err
+	}
+	return repository.(*testRepository).Add(id)
+}
+
+func TestUowDo(t *testing.T) {
+	t.Run("commits when fn succeeds", func(t *testing.T) {
+		u := newTestUow(t)
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			return addItem(ctx, u, "a")
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, countItems(t, u))
+	})
+
+	t.Run("rolls back when fn fails", func(t *testing.T) {
+		u := newTestUow(t)
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			require.Nil(t, addItem(ctx, u, "a"))
+			return errors.New("boom")
+		})
+
+		assert.EqualError(t, err, "boom")
+		assert.Equal(t, 0, countItems(t, u))
+	})
+
+	t.Run("gives every call its own transaction", func(t *testing.T) {
+		u := newTestUow(t)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := u.Do(context.Background(), func(ctx context.Context) error {
+					if err := addItem(ctx, u, fmt.Sprint(i)); err != nil {
+						return err
+					}
+					if i%2 == 0 {
+						return errors.New("rolled back")
+					}
+					return nil
+				})
+				if i%2 == 0 {
+					assert.EqualError(t, err, "rolled back")
+				} else {
+					assert.Nil(t, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 10, countItems(t, u))
+	})
+}
+
+func TestUowDoNested(t *testing.T) {
+	t.Run("commits inner work with the outer transaction", func(t *testing.T) {
+		u := newTestUow(t)
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			if err := addItem(ctx, u, "transfer"); err != nil {
+				return err
+			}
+			return u.Do(ctx, func(ctx context.Context) error {
+				return addItem(ctx, u, "fee")
+			})
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, countItems(t, u))
+	})
+
+	t.Run("rolls back only to the savepoint of a failed inner call", func(t *testing.T) {
+		u := newTestUow(t)
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			for _, id := range []string{"a", "b", "c"} {
+				innerErr := u.Do(ctx, func(ctx context.Context) error {
+					if err := addItem(ctx, u, id); err != nil {
+						return err
+					}
+					if id == "b" {
+						return errors.New("transfer b failed")
+					}
+					// Savepoints nest as deep as the calls do
+					return u.Do(ctx, func(ctx context.Context) error {
+						return addItem(ctx, u, id+"-fee")
+					})
+				})
+				if id == "b" {
+					assert.EqualError(t, innerErr, "transfer b failed")
+				} else if innerErr != nil {
+					return innerErr
+				}
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 4, countItems(t, u))
+	})
+
+	t.Run("rolls back inner work when the outer call fails", func(t *testing.T) {
+		u := newTestUow(t)
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			if err := u.Do(ctx, func(ctx context.Context) error {
+				return addItem(ctx, u, "transfer")
+			}); err != nil {
+				return err
+			}
+			return errors.New("fee failed")
+		})
+
+		assert.EqualError(t, err, "fee failed")
+		assert.Equal(t, 0, countItems(t, u))
+	})
+}
+
+func TestUowDoFailedRollback(t *testing.T) {
+	t.Run("keeps the error of fn classifiable", func(t *testing.T) {
+		u := newTestUow(t)
+		u.RetryPolicy.MaxAttempts = 1
+		deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			tx, _ := TxFromContext(ctx)
+			require.Nil(t, tx.Rollback())
+			return deadlock
+		})
+
+		assert.ErrorIs(t, err, deadlock)
+		assert.ErrorIs(t, err, sql.ErrTxDone)
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("keeps the error of an inner call classifiable", func(t *testing.T) {
+		u := newTestUow(t)
+		failure := errors.New("transfer failed")
+
+		err := u.Do(context.Background(), func(ctx context.Context) error {
+			innerErr := u.Do(ctx, func(ctx context.Context) error {
+				tx, _ := TxFromContext(ctx)
+				_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT uow_1")
+				require.Nil(t, err)
+				return failure
+			})
+			assert.ErrorIs(t, innerErr, failure)
+			assert.ErrorContains(t, innerErr, "rollback error: ")
+			return nil
+		})
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestUowGetRepository(t *testing.T) {
+	u := newTestUow(t)
+
+	_, err := u.GetRepository(context.Background(), "ItemRepository")
+	assert.ErrorIs(t, err, ErrNoTransaction)
+
+	err = u.Do(context.Background(), func(ctx context.Context) error {
+		_, err := u.GetRepository(ctx, "OrderRepository")
+		return err
+	})
+	assert.ErrorIs(t, err, ErrRepositoryNotRegistered)
+}
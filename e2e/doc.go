@@ -0,0 +1,4 @@
+// Package e2e runs the wallet and balance services together in one process,
+// connected by an in-memory broker, to test the path of a transfer from the
+// wallet's API to the balance service's projections.
+package e2e
@@ -0,0 +1,150 @@
+package e2e
+
+import (
+	balanceapp "balance/app"
+	balancedialect "balance/pkg/dialect"
+	balancemessaging "balance/pkg/messaging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+	walletapp "wallet/app"
+	walletdialect "wallet/pkg/dialect"
+	"wallet/pkg/events"
+	walletmessaging "wallet/pkg/messaging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bridge publishes the wallet's messages on the broker the balance service
+// consumes.
+type bridge struct {
+	broker *balancemessaging.MemoryBroker
+}
+
+func (b bridge) Publish(ctx context.Context, msg *walletmessaging.Message) error {
+	return b.broker.Publish(ctx, &balancemessaging.Message{
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: msg.Headers,
+	})
+}
+
+// services runs both services on SQLite databases seeded with the sample
+// accounts and returns the URLs of their APIs.
+func services(t *testing.T, encoder *events.CloudEventsEncoder) (string, string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	dir := t.TempDir()
+	broker := balancemessaging.NewMemoryBroker(3)
+
+	walletDb, walletDialect, err := walletdialect.Open("sqlite://" + filepath.Join(dir, "wallet.db"))
+	require.Nil(t, err)
+	t.Cleanup(func() { walletDb.Close() })
+	require.Nil(t, walletapp.Prepare(ctx, walletDb, walletDialect, true))
+	wallet := httptest.NewServer(walletapp.NewWebServer(ctx, walletDb, walletDialect, bridge{broker}, encoder, "").Handler())
+	t.Cleanup(wallet.Close)
+
+	balanceDb, balanceDialect, err := balancedialect.Open("sqlite://" + filepath.Join(dir, "balance.db"))
+	require.Nil(t, err)
+	t.Cleanup(func() { balanceDb.Close() })
+	require.Nil(t, balanceapp.Prepare(ctx, balanceDb, balanceDialect, true))
+	service := balanceapp.New(ctx, balanceDb, balanceDialect, broker, "")
+	consumed := make(chan error, 1)
+	go func() {
+		consumed <- broker.Subscriber("balance-service", balanceapp.Topics...).Subscribe(ctx, service.HandleMessage)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-consumed
+	})
+	balance := httptest.NewServer(service.WebServer.Handler())
+	t.Cleanup(balance.Close)
+
+	return wallet.URL, balance.URL
+}
+
+// getJSON decodes the response to a GET of url into v, leaving v alone when
+// the resource is not found.
+func getJSON(t *testing.T, url string, v interface{}) {
+	response, err := http.Get(url)
+	require.Nil(t, err)
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		require.Nil(t, json.NewDecoder(response.Body).Decode(v))
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	encoders := map[string]*events.CloudEventsEncoder{
+		"binary JSON":         events.NewCloudEventsEncoder("/wallet-service", false, events.JSONCodec{}),
+		"structured JSON":     events.NewCloudEventsEncoder("/wallet-service", true, events.JSONCodec{}),
+		"binary Protobuf":     events.NewCloudEventsEncoder("/wallet-service", false, events.ProtobufCodec{}),
+		"structured Protobuf": events.NewCloudEventsEncoder("/wallet-service", true, events.ProtobufCodec{}),
+	}
+	for name, encoder := range encoders {
+		t.Run("reaches the balance service as "+name, func(t *testing.T) {
+			walletURL, balanceURL := services(t, encoder)
+
+			body, err := json.Marshal(map[string]interface{}{
+				"account_id_from": walletapp.SampleAccountA,
+				"account_id_to":   walletapp.SampleAccountB,
+				"amount":          12.5,
+			})
+			require.Nil(t, err)
+			response, err := http.Post(walletURL+"/transactions", "application/json", bytes.NewReader(body))
+			require.Nil(t, err)
+			response.Body.Close()
+			require.Equal(t, http.StatusCreated, response.StatusCode)
+
+			var from, to struct {
+				Balance  float64 `json:"balance"`
+				Sequence int64   `json:"sequence"`
+			}
+			require.Eventually(t, func() bool {
+				getJSON(t, balanceURL+"/balances/"+balanceapp.SampleAccountA, &from)
+				getJSON(t, balanceURL+"/balances/"+balanceapp.SampleAccountB, &to)
+				return from.Sequence == 1 && to.Sequence == 1
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, 87.5, from.Balance)
+			assert.Equal(t, 112.5, to.Balance)
+
+			var transactions struct {
+				Transactions []struct {
+					CounterpartyAccountID string  `json:"counterparty_account_id"`
+					Direction             string  `json:"direction"`
+					Amount                float64 `json:"amount"`
+				} `json:"transactions"`
+			}
+			require.Eventually(t, func() bool {
+				getJSON(t, balanceURL+"/accounts/"+balanceapp.SampleAccountA+"/transactions", &transactions)
+				return len(transactions.Transactions) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, balanceapp.SampleAccountB, transactions.Transactions[0].CounterpartyAccountID)
+			assert.Equal(t, "debit", transactions.Transactions[0].Direction)
+			assert.Equal(t, 12.5, transactions.Transactions[0].Amount)
+		})
+	}
+}
+
+func TestSelfTransfer(t *testing.T) {
+	walletURL, _ := services(t, events.NewCloudEventsEncoder("/wallet-service", false, events.JSONCodec{}))
+
+	body, err := json.Marshal(map[string]interface{}{
+		"account_id_from": walletapp.SampleAccountA,
+		"account_id_to":   walletapp.SampleAccountA,
+		"amount":          12.5,
+	})
+	require.Nil(t, err)
+	response, err := http.Post(walletURL+"/transactions", "application/json", bytes.NewReader(body))
+	require.Nil(t, err)
+	response.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,102 @@
+// Package app wires the wallet service: its use cases, the events they
+// publish and the HTTP routes serving them. cmd/walletcore runs it against
+// the configured database and broker, and tests run it in process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wallet/internal/database"
+	"wallet/internal/event"
+	"wallet/internal/event/handler"
+	"wallet/internal/gateway"
+	createaccount "wallet/internal/usecase/create_account"
+	createclient "wallet/internal/usecase/create_client"
+	createtransaction "wallet/internal/usecase/create_transaction"
+	"wallet/internal/web"
+	"wallet/internal/web/webserver"
+	"wallet/pkg/dialect"
+	"wallet/pkg/events"
+	"wallet/pkg/messaging"
+	"wallet/pkg/uow"
+)
+
+// The sample accounts Prepare seeds, matching the ones the balance service
+// seeds.
+const (
+	SampleAccountA = database.SampleAccountA
+	SampleAccountB = database.SampleAccountB
+)
+
+// Prepare brings the schema of db up to date and, with seed, inserts the
+// sample accounts, leaving existing rows alone.
+func Prepare(ctx context.Context, db *sql.DB, d dialect.Dialect, seed bool) error {
+	migrator, err := database.NewMigrator(db, d)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	if err != nil || !seed {
+		return err
+	}
+	return database.Seed(db, d)
+}
+
+// NewWebServer returns the wallet service's web server listening on port,
+// publishing the events of its transfers through publisher.
+func NewWebServer(ctx context.Context, db *sql.DB, d dialect.Dialect, publisher messaging.Publisher, encoder *events.CloudEventsEncoder, port string) *webserver.WebServer {
+	// Publishing is retried briefly and each attempt bounded; panics are
+	// always recovered by the dispatcher
+	publishMiddleware := []events.Middleware{
+		events.Logging(),
+		events.Retry(3, 100*time.Millisecond),
+		events.Timeout(5 * time.Second),
+	}
+	eventDispatcher := events.NewEventDispatcher()
+	eventDispatcher.Register("TransactionCreated", handler.NewTransactionCreatedKafkaHandler(publisher, encoder), publishMiddleware...)
+	eventDispatcher.Register("BalanceUpdated", handler.NewUpdateBalanceKafkaHandler(publisher, encoder), publishMiddleware...)
+
+	clientDb := database.NewClientDB(db, d)
+	accountDb := database.NewAccountDB(db, d)
+
+	unitOfWork := uow.NewUow(ctx, db)
+	// A transfer that lost the race for an account's sequence reads it again
+	unitOfWork.RetryPolicy.Retryable = func(err error) bool {
+		return uow.IsRetryable(err) || errors.Is(err, database.ErrConcurrentUpdate)
+	}
+	unitOfWork.RetryPolicy.OnRetry = func(attempt int, err error) {
+		log.Printf("Retrying unit of work (attempt %d): %v", attempt, err)
+	}
+	uow.Register(unitOfWork, func(tx *sql.Tx) gateway.AccountGateway {
+		return database.NewAccountDB(tx, d)
+	})
+	uow.Register(unitOfWork, func(tx *sql.Tx) gateway.TransactionGateway {
+		return database.NewTransactionDB(tx, d)
+	})
+
+	createClientUseCase := createclient.NewCreateClientUseCase(clientDb)
+	createAccountUseCase := createaccount.NewCreateAccountUseCase(accountDb, clientDb)
+	createTransactionUseCase := createtransaction.NewCreateTransactionUseCase(unitOfWork, eventDispatcher, events.NewFactory(event.NewTransactionCreated), events.NewFactory(event.NewBalanceUpdated))
+
+	webserver := webserver.NewWebServer(port)
+
+	clientHandler := web.NewWebClientHandler(*createClientUseCase)
+	accountHandler := web.NewWebAccountHandler(*createAccountUseCase)
+	transactionHandler := web.NewWebTransactionHandler(*createTransactionUseCase)
+
+	webserver.AddHandler("/clients", clientHandler.CreateClient)
+	webserver.AddHandler("/accounts", accountHandler.CreateAccount)
+	webserver.AddHandler("/transactions", transactionHandler.CreateTransaction)
+	webserver.AddHandler("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	return webserver
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"wallet/pkg/kafka"
+	"wallet/pkg/messaging"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+	_ "modernc.org/sqlite"
+)
+
+// eventLogPartitions must match the balance service when sharing an event log.
+const eventLogPartitions = 3
+
+// eventLogPath returns the SQLite event log shared with the balance service
+// that replaces Kafka, or "" for Kafka: the one in the standalone directory,
+// or with MESSAGE_BROKER=sqlite the one at EVENT_LOG_PATH.
+func eventLogPath(standalone string) string {
+	if standalone != "" {
+		return filepath.Join(standalone, "events.db")
+	}
+	if os.Getenv("MESSAGE_BROKER") != "sqlite" {
+		return ""
+	}
+	if path := os.Getenv("EVENT_LOG_PATH"); path != "" {
+		return path
+	}
+	return "events.db"
+}
+
+// kafkaBootstrapServers returns the Kafka brokers to connect to, from
+// KAFKA_BOOTSTRAP_SERVERS.
+func kafkaBootstrapServers() string {
+	if servers := os.Getenv("KAFKA_BOOTSTRAP_SERVERS"); servers != "" {
+		return servers
+	}
+	return "kafka:29092"
+}
+
+// openPublisher connects to the event log at path, or to Kafka when path is
+// empty. The returned function flushes and releases it.
+func openPublisher(path string) (messaging.Publisher, func(), error) {
+	if path != "" {
+		db, err := sql.Open("sqlite", messaging.SQLiteDSN(path))
+		if err != nil {
+			return nil, nil, err
+		}
+		broker, err := messaging.NewSQLiteBroker(db, eventLogPartitions)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		fmt.Println("Using the SQLite event log at", path)
+		return broker, func() { db.Close() }, nil
+	}
+
+	producer, err := kafka.NewKafkaProducer(&ckafka.ConfigMap{
+		"bootstrap.servers": kafkaBootstrapServers(),
+		"group.id":          "wallet",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return kafka.NewPublisher(producer), producer.Close, nil
+}
@@ -2,16 +2,23 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"wallet/internal/entity"
+	"wallet/pkg/dialect"
 )
 
+// ErrConcurrentUpdate is returned when another transaction stamped the
+// sequence of an account after it was read.
+var ErrConcurrentUpdate = errors.New("account was updated concurrently")
+
 type AccountDB struct {
-	DB *sql.DB
+	DB      DBTX
+	Dialect dialect.Dialect
 }
 
-func NewAccountDB(db *sql.DB) *AccountDB {
-	return &AccountDB{DB: db}
+func NewAccountDB(db DBTX, d dialect.Dialect) *AccountDB {
+	return &AccountDB{DB: db, Dialect: d}
 }
 
 func (a *AccountDB) FindById(id string) (*entity.Account, error) {
@@ -23,6 +30,7 @@ func (a *AccountDB) FindById(id string) (*entity.Account, error) {
 				a.id, 
 				a.client_id, 
 				a.balance, 
+				a.sequence, 
 				a.created_at, 
 				c.id, 
 				c.name, 
@@ -30,13 +38,14 @@ func (a *AccountDB) FindById(id string) (*entity.Account, error) {
 				c.created_at 
 			  FROM accounts a INNER JOIN clients c 
 			  ON a.client_id = c.id 
-			  WHERE a.id = ?`
+			  WHERE a.id = ?` + forUpdate(a.DB, a.Dialect)
 
-	row := a.DB.QueryRow(query, id)
+	row := a.DB.QueryRow(a.Dialect.Rebind(query), id)
 	err := row.Scan(
 		&account.Id,
 		&account.Client.Id,
 		&account.Balance,
+		&account.Sequence,
 		&account.CreatedAt,
 		&client.Id,
 		&client.Name,
@@ -54,7 +63,7 @@ func (a *AccountDB) Save(account *entity.Account) error {
 	// Check if the client exists
 	clientQuery := `SELECT id FROM clients WHERE id = ?`
 	var clientId string
-	err := a.DB.QueryRow(clientQuery, account.Client.Id).Scan(&clientId)
+	err := a.DB.QueryRow(a.Dialect.Rebind(clientQuery), account.Client.Id).Scan(&clientId)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("client does not exist")
 	}
@@ -62,21 +71,32 @@ func (a *AccountDB) Save(account *entity.Account) error {
 	// Check if the account already exists
 	accountQuery := `SELECT id FROM accounts WHERE id = ?`
 	var accountId string
-	err = a.DB.QueryRow(accountQuery, account.Id).Scan(&accountId)
+	err = a.DB.QueryRow(a.Dialect.Rebind(accountQuery), account.Id).Scan(&accountId)
 	if err == nil {
 		return fmt.Errorf("account already exists")
 	}
 	// Insert the account
-	insertQuery := `INSERT INTO accounts (id, client_id, balance, created_at) VALUES (?, ?, ?, ?)`
-	a.DB.Exec(insertQuery, account.Id, account.Client.Id, account.Balance, account.CreatedAt)
+	insertQuery := `INSERT INTO accounts (id, client_id, balance, sequence, created_at) VALUES (?, ?, ?, ?, ?)`
+	a.DB.Exec(a.Dialect.Rebind(insertQuery), account.Id, account.Client.Id, account.Balance, account.Sequence, account.CreatedAt)
 	return nil
 }
 
+// UpdateBalance writes the balance and sequence of an account. FindById locks
+// the row inside a unit of work; the sequence check also refuses to stamp a
+// sequence another transaction already stamped, e.g. on SQLite or when the
+// account was read outside one.
 func (a *AccountDB) UpdateBalance(account *entity.Account) error {
-	updateQuery := `UPDATE accounts SET balance = ? WHERE id = ?`
-	_, err := a.DB.Exec(updateQuery, account.Balance, account.Id)
+	updateQuery := `UPDATE accounts SET balance = ?, sequence = ? WHERE id = ? AND sequence < ?`
+	result, err := a.DB.Exec(a.Dialect.Rebind(updateQuery), account.Balance, account.Sequence, account.Id, account.Sequence)
 	if err != nil {
 		return fmt.Errorf("failed to update account balance: %w", err)
 	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w: account %s at sequence %d", ErrConcurrentUpdate, account.Id, account.Sequence)
+	}
 	return nil
 }
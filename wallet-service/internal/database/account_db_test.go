@@ -1,9 +1,13 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"wallet/internal/entity"
+	"wallet/pkg/dialect"
+	"wallet/pkg/uow"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -23,27 +27,13 @@ func (suite *AccountDBTestSuite) SetupSuite() {
 	if err != nil {
 		suite.T().Fatal(err)
 	}
+	// Keep the in-memory database on one connection, which transactions share
+	db.SetMaxOpenConns(1)
 	suite.db = db
+	migrateTestDB(suite.T(), db)
 
-	// Create clients table
-	db.Exec(`CREATE TABLE clients (
-        id varchar(255) PRIMARY KEY, 
-        name varchar(255), 
-        email varchar(255), 
-        created_at date
-    )`)
-
-	// Create accounts table
-	db.Exec(`CREATE TABLE accounts (
-        id varchar(255) PRIMARY KEY, 
-        client_id varchar(255), 
-        balance float, 
-        created_at date,
-        FOREIGN KEY (client_id) REFERENCES clients(id)
-    )`)
-
-	suite.accountDB = NewAccountDB(suite.db)
-	suite.clientDB = NewClientDB(suite.db)
+	suite.accountDB = NewAccountDB(suite.db, dialect.SQLite)
+	suite.clientDB = NewClientDB(suite.db, dialect.SQLite)
 }
 
 func (suite *AccountDBTestSuite) TearDownSuite() {
@@ -112,6 +102,7 @@ func (suite *AccountDBTestSuite) TestFindById() {
 	assert.NotNil(suite.T(), account)
 	assert.Equal(suite.T(), expectedAccount.Id, account.Id)
 	assert.Equal(suite.T(), expectedAccount.Balance, account.Balance)
+	assert.Equal(suite.T(), expectedAccount.Sequence, account.Sequence)
 	assert.NotNil(suite.T(), account.Client)
 	assert.Equal(suite.T(), client.Id, account.Client.Id)
 	assert.Equal(suite.T(), client.Name, account.Client.Name)
@@ -136,6 +127,90 @@ func (suite *AccountDBTestSuite) TestSaveWithNonExistentClient() {
 	assert.Error(suite.T(), err)
 }
 
+func (suite *AccountDBTestSuite) TestUpdateBalance() {
+	client, _ := entity.NewClient("Carol White", "carol@example.com")
+	err := suite.clientDB.Save(client)
+	assert.Nil(suite.T(), err)
+
+	account, _ := entity.NewAccount(client)
+	err = suite.accountDB.Save(account)
+	assert.Nil(suite.T(), err)
+
+	account.Credit(100.0)
+	account.Debit(40.0)
+	err = suite.accountDB.UpdateBalance(account)
+	assert.Nil(suite.T(), err)
+
+	saved, err := suite.accountDB.FindById(account.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 60.0, saved.Balance)
+	assert.Equal(suite.T(), int64(2), saved.Sequence)
+}
+
+func (suite *AccountDBTestSuite) TestUpdateBalanceRejectsStaleSequence() {
+	client, _ := entity.NewClient("Erin Green", "erin@example.com")
+	suite.Nil(suite.clientDB.Save(client))
+	account, _ := entity.NewAccount(client)
+	suite.Nil(suite.accountDB.Save(account))
+
+	// Two transfers read the account at the same sequence
+	first, err := suite.accountDB.FindById(account.Id)
+	suite.Nil(err)
+	second, err := suite.accountDB.FindById(account.Id)
+	suite.Nil(err)
+
+	first.Credit(100.0)
+	suite.Nil(suite.accountDB.UpdateBalance(first))
+	second.Credit(50.0)
+	suite.ErrorIs(suite.accountDB.UpdateBalance(second), ErrConcurrentUpdate)
+
+	saved, err := suite.accountDB.FindById(account.Id)
+	suite.Nil(err)
+	suite.Equal(100.0, saved.Balance)
+	suite.Equal(int64(1), saved.Sequence)
+}
+
+func (suite *AccountDBTestSuite) TestUpdateBalanceRolledBackWithUnitOfWork() {
+	ctx := context.Background()
+	client, _ := entity.NewClient("Dave Brown", "dave@example.com")
+	suite.Nil(suite.clientDB.Save(client))
+	accountFrom, _ := entity.NewAccount(client)
+	accountFrom.Credit(100.0)
+	suite.Nil(suite.accountDB.Save(accountFrom))
+	accountTo, _ := entity.NewAccount(client)
+	suite.Nil(suite.accountDB.Save(accountTo))
+
+	unitOfWork := uow.NewUow(ctx, suite.db)
+	unitOfWork.Register("AccountRepository", func(tx *sql.Tx) interface{} {
+		return NewAccountDB(tx, dialect.SQLite)
+	})
+	err := unitOfWork.Do(ctx, func(ctx context.Context) error {
+		repository, err := unitOfWork.GetRepository(ctx, "AccountRepository")
+		suite.Nil(err)
+		accountDB := repository.(*AccountDB)
+
+		suite.Nil(accountFrom.Debit(40.0))
+		accountTo.Credit(40.0)
+		suite.Nil(accountDB.UpdateBalance(accountFrom))
+		suite.Nil(accountDB.UpdateBalance(accountTo))
+
+		// The update is visible inside the transaction
+		updated, err := accountDB.FindById(accountFrom.Id)
+		suite.Nil(err)
+		suite.Equal(60.0, updated.Balance)
+		return errors.New("saving the transaction failed")
+	})
+	suite.EqualError(err, "saving the transaction failed")
+
+	savedFrom, err := suite.accountDB.FindById(accountFrom.Id)
+	suite.Nil(err)
+	suite.Equal(100.0, savedFrom.Balance)
+	suite.Equal(int64(1), savedFrom.Sequence)
+	savedTo, err := suite.accountDB.FindById(accountTo.Id)
+	suite.Nil(err)
+	suite.Equal(0.0, savedTo.Balance)
+}
+
 func TestAccountDBTestSuite(t *testing.T) {
 	suite.Run(t, new(AccountDBTestSuite))
 }
@@ -1,21 +1,22 @@
 package database
 
 import (
-	"database/sql"
 	"wallet/internal/entity"
+	"wallet/pkg/dialect"
 )
 
 type ClientDB struct {
-	DB *sql.DB
+	DB      DBTX
+	Dialect dialect.Dialect
 }
 
-func NewClientDB(db *sql.DB) *ClientDB {
-	return &ClientDB{DB: db}
+func NewClientDB(db DBTX, d dialect.Dialect) *ClientDB {
+	return &ClientDB{DB: db, Dialect: d}
 }
 
 func (c *ClientDB) Get(id string) (*entity.Client, error) {
 	query := `SELECT id, name, email, created_at FROM clients WHERE id = ?`
-	row := c.DB.QueryRow(query, id)
+	row := c.DB.QueryRow(c.Dialect.Rebind(query), id)
 
 	client := &entity.Client{}
 	err := row.Scan(&client.Id, &client.Name, &client.Email, &client.CreatedAt)
@@ -28,7 +29,7 @@ func (c *ClientDB) Get(id string) (*entity.Client, error) {
 
 func (c *ClientDB) Save(client *entity.Client) error {
 	query := `INSERT INTO clients (id, name, email, created_at) VALUES (?, ?, ?, ?)`
-	_, err := c.DB.Exec(query, client.Id, client.Name, client.Email, client.CreatedAt)
+	_, err := c.DB.Exec(c.Dialect.Rebind(query), client.Id, client.Name, client.Email, client.CreatedAt)
 	if err != nil {
 		return err
 	}
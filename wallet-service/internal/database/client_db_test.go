@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"testing"
 	"wallet/internal/entity"
+	"wallet/pkg/dialect"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -24,9 +25,9 @@ func (suite *ClientDBTestSuite) SetupSuite() {
 		suite.T().Fatal(err)
 	}
 	suite.db = db
-	db.Exec("CREATE TABLE clients (id varchar(255) PRIMARY KEY, name varchar(255), email varchar(255), created_at date)")
+	migrateTestDB(suite.T(), db)
 
-	suite.clientDB = NewClientDB(suite.db)
+	suite.clientDB = NewClientDB(suite.db, dialect.SQLite)
 }
 
 func (suite *ClientDBTestSuite) TearDownSuite() {
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+	"wallet/pkg/dialect"
+	"wallet/pkg/migrate"
+)
+
+// MigrationsTable records the applied migrations. It is named after the
+// service because the balance service migrates the same database.
+const MigrationsTable = "wallet_schema_migrations"
+
+// The scripts in migrations run on MySQL and SQLite; PostgreSQL has its own
+// in migrations/postgres.
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrations embed.FS
+
+// NewMigrator returns a migrator for the schema of the wallet service.
+func NewMigrator(db *sql.DB, d dialect.Dialect) (*migrate.Migrator, error) {
+	dir := "migrations"
+	if d == dialect.Postgres {
+		dir = "migrations/postgres"
+	}
+	scripts, err := fs.Sub(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(db, d, MigrationsTable, scripts)
+}
@@ -1,12 +1,12 @@
--- Wallet Service Tables
-CREATE TABLE IF NOT EXISTS clients (
+-- The schema .docker/init-db created before migrations were tracked
+CREATE TABLE clients (
     id VARCHAR(255) PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     email VARCHAR(255) NOT NULL,
     created_at DATETIME NOT NULL
 );
 
-CREATE TABLE IF NOT EXISTS accounts (
+CREATE TABLE accounts (
     id VARCHAR(255) PRIMARY KEY,
     client_id VARCHAR(255) NOT NULL,
     balance DECIMAL(15,2) NOT NULL,
@@ -14,7 +14,7 @@ CREATE TABLE IF NOT EXISTS accounts (
     FOREIGN KEY (client_id) REFERENCES clients(id)
 );
 
-CREATE TABLE IF NOT EXISTS transactions (
+CREATE TABLE transactions (
     id VARCHAR(255) PRIMARY KEY,
     account_id_from VARCHAR(255) NOT NULL,
     account_id_to VARCHAR(255) NOT NULL,
@@ -22,4 +22,4 @@ CREATE TABLE IF NOT EXISTS transactions (
     created_at DATETIME NOT NULL,
     FOREIGN KEY (account_id_from) REFERENCES accounts(id),
     FOREIGN KEY (account_id_to) REFERENCES accounts(id)
-);
\ No newline at end of file
+);
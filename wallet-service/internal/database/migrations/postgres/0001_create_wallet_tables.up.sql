@@ -0,0 +1,25 @@
+-- The schema .docker/init-db created before migrations were tracked
+CREATE TABLE clients (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE accounts (
+    id VARCHAR(255) PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (client_id) REFERENCES clients(id)
+);
+
+CREATE TABLE transactions (
+    id VARCHAR(255) PRIMARY KEY,
+    account_id_from VARCHAR(255) NOT NULL,
+    account_id_to VARCHAR(255) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (account_id_from) REFERENCES accounts(id),
+    FOREIGN KEY (account_id_to) REFERENCES accounts(id)
+);
@@ -0,0 +1,3 @@
+-- Incremented with every balance change and published with it, so consumers
+-- can skip stale updates
+ALTER TABLE accounts ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+	"wallet/pkg/dialect"
+)
+
+// Sample accounts used by the README and api/client.http. The balance
+// service seeds the same ids.
+const (
+	SampleAccountA = "7ebc23f5-dd1e-4d93-9490-9fce5052a5f5"
+	SampleAccountB = "dff2d137-bba6-4138-81b9-3da7567f122b"
+)
+
+// Seed inserts two sample clients with an account of 100 each. Rows that
+// already exist are left alone, so it is safe to run on every start.
+func Seed(db *sql.DB, d dialect.Dialect) error {
+	now := time.Now().UTC()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	err = seedRows(tx, d.Upsert("clients", []string{"id", "name", "email", "created_at"}, []string{"id"}, nil), [][]interface{}{
+		{"b7295961-c51c-438a-90e2-78e62f18b726", "Luis Garavaso", "j.j@email.com", now},
+		{"31f52dea-7856-42dc-9364-f508fa74d5d7", "Jane Doe", "jane.j@email.com", now},
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = seedRows(tx, d.Upsert("accounts", []string{"id", "client_id", "balance", "sequence", "created_at"}, []string{"id"}, nil), [][]interface{}{
+		{SampleAccountA, "b7295961-c51c-438a-90e2-78e62f18b726", 100.0, 0, now},
+		{SampleAccountB, "31f52dea-7856-42dc-9364-f508fa74d5d7", 100.0, 0, now},
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func seedRows(tx *sql.Tx, query string, rows [][]interface{}) error {
+	for _, row := range rows {
+		if _, err := tx.Exec(query, row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
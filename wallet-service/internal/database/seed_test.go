@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"wallet/pkg/dialect"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	migrateTestDB(t, db)
+
+	require.Nil(t, Seed(db, dialect.SQLite))
+	accountDB := NewAccountDB(db, dialect.SQLite)
+	account, err := accountDB.FindById(SampleAccountA)
+	require.Nil(t, err)
+	require.Nil(t, account.Debit(10))
+	require.Nil(t, accountDB.UpdateBalance(account))
+	// Seeding again leaves existing rows alone
+	require.Nil(t, Seed(db, dialect.SQLite))
+
+	account, err = accountDB.FindById(SampleAccountA)
+	require.Nil(t, err)
+	assert.Equal(t, 90.0, account.Balance)
+	account, err = accountDB.FindById(SampleAccountB)
+	require.Nil(t, err)
+	assert.Equal(t, 100.0, account.Balance)
+	assert.Equal(t, "Jane Doe", account.Client.Name)
+}
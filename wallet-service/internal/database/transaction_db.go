@@ -1,30 +1,32 @@
 package database
 
 import (
-	"database/sql"
 	"fmt"
 	"wallet/internal/entity"
+	"wallet/pkg/dialect"
 )
 
 type TransactionDB struct {
-	DB *sql.DB
+	DB      DBTX
+	Dialect dialect.Dialect
 }
 
-func NewTransactionDB(db *sql.DB) *TransactionDB {
-	return &TransactionDB{DB: db}
+func NewTransactionDB(db DBTX, d dialect.Dialect) *TransactionDB {
+	return &TransactionDB{DB: db, Dialect: d}
 }
 
 func (t *TransactionDB) Create(transaction *entity.Transaction) error {
 	// Check if the account_from exists
+	accountExists := t.Dialect.Rebind(`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = ?)`)
 	var accountFromExists bool
-	t.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = ?)`, transaction.AccountFrom.Id).Scan(&accountFromExists)
+	t.DB.QueryRow(accountExists, transaction.AccountFrom.Id).Scan(&accountFromExists)
 	if !accountFromExists {
 		return fmt.Errorf("account_from with id %s does not exist", transaction.AccountFrom.Id)
 	}
 
 	// Check if the account_to exists
 	var accountToExists bool
-	t.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = ?)`, transaction.AccountTo.Id).Scan(&accountToExists)
+	t.DB.QueryRow(accountExists, transaction.AccountTo.Id).Scan(&accountToExists)
 
 	if !accountToExists {
 		return fmt.Errorf("account_to with id %s does not exist", transaction.AccountTo.Id)
@@ -32,7 +34,7 @@ func (t *TransactionDB) Create(transaction *entity.Transaction) error {
 
 	// Proceed with the transaction creation
 	query := `INSERT INTO transactions (id, account_id_from, account_id_to, amount, created_at) VALUES (?, ?, ?, ?, ?)`
-	_, err := t.DB.Exec(query,
+	_, err := t.DB.Exec(t.Dialect.Rebind(query),
 		transaction.Id,
 		transaction.AccountFrom.Id,
 		transaction.AccountTo.Id,
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"testing"
 	"wallet/internal/entity"
+	"wallet/pkg/dialect"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -28,36 +29,11 @@ func (suite *TransactionDBTestSuite) SetupSuite() {
 		suite.T().Fatal(err)
 	}
 	suite.db = db
+	migrateTestDB(suite.T(), db)
 
-	// Create necessary tables for testing
-	db.Exec(`CREATE TABLE clients (
-        id varchar(255) PRIMARY KEY, 
-        name varchar(255), 
-        email varchar(255), 
-        created_at date
-    )`)
-
-	db.Exec(`CREATE TABLE accounts (
-        id varchar(255) PRIMARY KEY, 
-        client_id varchar(255), 
-        balance float, 
-        created_at date,
-        FOREIGN KEY (client_id) REFERENCES clients(id)
-    )`)
-
-	db.Exec(`CREATE TABLE transactions (
-        id varchar(255) PRIMARY KEY,
-        account_id_from varchar(255),
-        account_id_to varchar(255),
-        amount float,
-        created_at date,
-        FOREIGN KEY (account_id_from) REFERENCES accounts(id),
-        FOREIGN KEY (account_id_to) REFERENCES accounts(id)
-    )`)
-
-	suite.transactionDB = NewTransactionDB(suite.db)
-	suite.accountDB = NewAccountDB(suite.db)
-	suite.clientDB = NewClientDB(suite.db)
+	suite.transactionDB = NewTransactionDB(suite.db, dialect.SQLite)
+	suite.accountDB = NewAccountDB(suite.db, dialect.SQLite)
+	suite.clientDB = NewClientDB(suite.db, dialect.SQLite)
 }
 
 func (suite *TransactionDBTestSuite) SetupTest() {
@@ -16,6 +16,7 @@ type Account struct {
 	Id        string    `json:"id"`
 	Client    *Client   `json:"client"`
 	Balance   float64   `json:"balance"`
+	Sequence  int64     `json:"sequence"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -46,6 +47,7 @@ func (account *Account) Validate() error {
 
 func (a *Account) Credit(amount float64) {
 	a.Balance += amount
+	a.Sequence++
 	a.UpdatedAt = time.Now()
 }
 
@@ -54,6 +56,7 @@ func (a *Account) Debit(amount float64) error {
 		return errors.New(ErrInsufficientBalance)
 	}
 	a.Balance -= amount
+	a.Sequence++
 	a.UpdatedAt = time.Now()
 	return nil
 }
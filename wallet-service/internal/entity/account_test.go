@@ -47,3 +47,25 @@ func TestAccountDebit_MustFailWhenBalanceIsInsufficient(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, ErrInsufficientBalance, err.Error())
 }
+
+func TestAccountSequence_IncrementsOnEveryBalanceChange(t *testing.T) {
+	client, _ := NewClient("John", "j@email.com")
+	account, _ := NewAccount(client)
+	assert.Equal(t, int64(0), account.Sequence)
+
+	account.Credit(100.0)
+	assert.Equal(t, int64(1), account.Sequence)
+
+	account.Debit(30.0)
+	assert.Equal(t, int64(2), account.Sequence)
+}
+
+func TestAccountSequence_DoesNotChangeWhenDebitFails(t *testing.T) {
+	client, _ := NewClient("John", "j@email.com")
+	account, _ := NewAccount(client)
+	account.Credit(100.0)
+
+	err := account.Debit(150.0)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(1), account.Sequence)
+}
@@ -48,7 +48,9 @@ func (transaction *Transaction) Validate() error {
 	if transaction.Amount <= 0 {
 		return errors.New(ErrInvalidAmount)
 	}
-	if transaction.AccountFrom == transaction.AccountTo {
+	// Accounts are loaded separately, so the same account can come as two
+	// instances
+	if transaction.AccountFrom.Id == transaction.AccountTo.Id {
 		return errors.New(ErrInvalidTransaction)
 	}
 	if transaction.AccountFrom.Balance < transaction.Amount {
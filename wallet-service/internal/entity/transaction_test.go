@@ -82,6 +82,20 @@ func TestCreateNewTransaction_MustFailWhenAccountsAreTheSame(t *testing.T) {
 	assert.Equal(t, ErrInvalidTransaction, err.Error())
 }
 
+func TestCreateNewTransaction_MustFailWhenAccountsHaveTheSameId(t *testing.T) {
+	client1, _ := NewClient("John", "john@email.com")
+	account1, _ := NewAccount(client1)
+	account1.Credit(100.0)
+	loadedAgain := *account1
+
+	transaction, err := NewTransaction(account1, &loadedAgain, 50.0)
+
+	assert.Nil(t, transaction)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrInvalidTransaction, err.Error())
+	assert.Equal(t, 100.0, account1.Balance)
+}
+
 func TestCreateNewTransaction_MustFailWhenBalanceIsInsufficient(t *testing.T) {
 	client1, _ := NewClient("John", "john@email.com")
 	account1, _ := NewAccount(client1)
@@ -1,30 +1,39 @@
 package event
 
-import "time"
+import (
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// BalanceUpdated is created with its payload for a single dispatch and not modified
+// afterwards.
 type BalanceUpdated struct {
-	Name    string      `json:"name"`
-	Payload interface{} `json:"payload"`
+	id       string
+	payload  interface{}
+	dateTime time.Time
 }
 
-func NewBalanceUpdated() *BalanceUpdated {
+func NewBalanceUpdated(payload interface{}) *BalanceUpdated {
 	return &BalanceUpdated{
-		Name: "BalanceUpdated",
+		id:       uuid.New().String(),
+		payload:  payload,
+		dateTime: time.Now(),
 	}
 }
 
-func (e *BalanceUpdated) GetName() string {
-	return e.Name
+func (e *BalanceUpdated) GetID() string {
+	return e.id
 }
 
-func (e *BalanceUpdated) GetPayload() interface{} {
-	return e.Payload
+func (e *BalanceUpdated) GetName() string {
+	return "BalanceUpdated"
 }
 
-func (e *BalanceUpdated) SetPayload(payload interface{}) {
-	e.Payload = payload
+func (e *BalanceUpdated) GetPayload() interface{} {
+	return e.payload
 }
 
 func (e *BalanceUpdated) GetDateTime() time.Time {
-	return time.Now()
+	return e.dateTime
 }
@@ -1,24 +1,28 @@
 package handler
 
 import (
+	"context"
 	"fmt"
-	"sync"
 	"wallet/pkg/events"
-	"wallet/pkg/kafka"
+	"wallet/pkg/messaging"
 )
 
 type UpdateBalanceKafkaHandler struct {
-	Kafka *kafka.Producer
+	Publisher messaging.Publisher
+	Encoder   *events.CloudEventsEncoder
 }
 
-func NewUpdateBalanceKafkaHandler(kafka *kafka.Producer) *UpdateBalanceKafkaHandler {
+func NewUpdateBalanceKafkaHandler(publisher messaging.Publisher, encoder *events.CloudEventsEncoder) *UpdateBalanceKafkaHandler {
 	return &UpdateBalanceKafkaHandler{
-		Kafka: kafka,
+		Publisher: publisher,
+		Encoder:   encoder,
 	}
 }
 
-func (h *UpdateBalanceKafkaHandler) Handle(message events.EventInterface, wg *sync.WaitGroup) {
-	defer wg.Done()
-	h.Kafka.Publish(message, nil, "balances")
+func (h *UpdateBalanceKafkaHandler) Handle(ctx context.Context, message events.EventInterface) error {
+	if err := publishEvent(ctx, h.Publisher, h.Encoder, "balances", message); err != nil {
+		return fmt.Errorf("UpdateBalanceKafkaHandler: failed to publish: %w", err)
+	}
 	fmt.Println("UpdateBalanceKafkaHandler: ", message.GetPayload())
+	return nil
 }
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"wallet/internal/event"
+	"wallet/pkg/events"
+	"wallet/pkg/messaging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keyedPayload struct {
+	AccountId string  `json:"account_id"`
+	Balance   float64 `json:"balance"`
+}
+
+func (p keyedPayload) PartitionKey() string {
+	return p.AccountId
+}
+
+func TestUpdateBalanceKafkaHandler_Handle(t *testing.T) {
+	t.Run("publishes the event keyed by account", func(t *testing.T) {
+		broker := messaging.NewMemoryBroker(1)
+		message := event.NewBalanceUpdated(keyedPayload{AccountId: "account1", Balance: 50})
+
+		err := NewUpdateBalanceKafkaHandler(broker, events.NewCloudEventsEncoder("/wallet-service", false, events.JSONCodec{})).Handle(context.Background(), message)
+		require.Nil(t, err)
+
+		published := broker.Messages("balances")
+		require.Len(t, published, 1)
+		assert.Equal(t, []byte("account1"), published[0].Key)
+		assert.JSONEq(t, `{"account_id":"account1","balance":50}`, string(published[0].Value))
+		assert.Equal(t, "application/json", published[0].Headers[events.HeaderContentType])
+		assert.Equal(t, "wallet.events.BalanceUpdated", published[0].Headers[events.HeaderCloudEventsType])
+		assert.Equal(t, "/wallet-service", published[0].Headers[events.HeaderCloudEventsSource])
+	})
+
+	t.Run("encodes the event with the handler's encoder", func(t *testing.T) {
+		broker := messaging.NewMemoryBroker(1)
+		message := event.NewBalanceUpdated(keyedPayload{AccountId: "account1", Balance: 50})
+
+		err := NewUpdateBalanceKafkaHandler(broker, events.NewCloudEventsEncoder("/wallet-service", true, events.ProtobufCodec{})).Handle(context.Background(), message)
+		require.Nil(t, err)
+
+		published := broker.Messages("balances")
+		require.Len(t, published, 1)
+		assert.Equal(t, events.ContentTypeCloudEventsJSON, published[0].Headers[events.HeaderContentType])
+		attributes, payload, err := events.DecodeMessage(published[0].Headers, published[0].Value)
+		assert.Nil(t, err)
+		assert.Equal(t, "BalanceUpdated", attributes.Name)
+		assert.Equal(t, "account1", payload.(map[string]interface{})["account_id"])
+	})
+
+	t.Run("does not publish events that do not match their schema", func(t *testing.T) {
+		broker := messaging.NewMemoryBroker(1)
+		message := event.NewBalanceUpdated(keyedPayload{})
+
+		err := NewUpdateBalanceKafkaHandler(broker, events.NewCloudEventsEncoder("/wallet-service", false, events.JSONCodec{})).Handle(context.Background(), message)
+		assert.ErrorContains(t, err, "does not match its schema")
+
+		assert.Empty(t, broker.Messages("balances"))
+	})
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"context"
+	"wallet/pkg/events"
+	"wallet/pkg/messaging"
+)
+
+// publishEvent encodes message as a CloudEvent and publishes it to topic.
+// Keying keeps events of the same account on one partition, in order.
+func publishEvent(ctx context.Context, publisher messaging.Publisher, encoder *events.CloudEventsEncoder, topic string, message events.EventInterface) error {
+	// Never publish an event that consumers would reject
+	if err := events.ValidateEvent(message); err != nil {
+		return err
+	}
+	value, headers, err := encoder.Encode(message)
+	if err != nil {
+		return err
+	}
+	return publisher.Publish(ctx, &messaging.Message{
+		Topic:   topic,
+		Key:     events.PartitionKey(message),
+		Value:   value,
+		Headers: headers,
+	})
+}
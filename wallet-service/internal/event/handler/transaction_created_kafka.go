@@ -1,24 +1,28 @@
 package handler
 
 import (
+	"context"
 	"fmt"
-	"sync"
 	"wallet/pkg/events"
-	"wallet/pkg/kafka"
+	"wallet/pkg/messaging"
 )
 
 type TransactionCreatedKafkaHandler struct {
-	Kafka *kafka.Producer
+	Publisher messaging.Publisher
+	Encoder   *events.CloudEventsEncoder
 }
 
-func NewTransactionCreatedKafkaHandler(kafka *kafka.Producer) *TransactionCreatedKafkaHandler {
+func NewTransactionCreatedKafkaHandler(publisher messaging.Publisher, encoder *events.CloudEventsEncoder) *TransactionCreatedKafkaHandler {
 	return &TransactionCreatedKafkaHandler{
-		Kafka: kafka,
+		Publisher: publisher,
+		Encoder:   encoder,
 	}
 }
 
-func (h *TransactionCreatedKafkaHandler) Handle(message events.EventInterface, wg *sync.WaitGroup) {
-	defer wg.Done()
-	h.Kafka.Publish(message, nil, "transactions")
+func (h *TransactionCreatedKafkaHandler) Handle(ctx context.Context, message events.EventInterface) error {
+	if err := publishEvent(ctx, h.Publisher, h.Encoder, "transactions", message); err != nil {
+		return fmt.Errorf("TransactionCreatedKafkaHandler: failed to publish: %w", err)
+	}
 	fmt.Println("TransactionCreatedKafkaHandler: ", message.GetPayload())
+	return nil
 }
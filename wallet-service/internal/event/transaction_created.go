@@ -1,30 +1,39 @@
 package event
 
-import "time"
+import (
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// TransactionCreated is created with its payload for a single dispatch and not modified
+// afterwards.
 type TransactionCreated struct {
-	Name    string      `json:"name"`
-	Payload interface{} `json:"payload"`
+	id       string
+	payload  interface{}
+	dateTime time.Time
 }
 
-func NewTransactionCreated() *TransactionCreated {
+func NewTransactionCreated(payload interface{}) *TransactionCreated {
 	return &TransactionCreated{
-		Name: "TransactionCreated",
+		id:       uuid.New().String(),
+		payload:  payload,
+		dateTime: time.Now(),
 	}
 }
 
-func (e *TransactionCreated) GetName() string {
-	return e.Name
+func (e *TransactionCreated) GetID() string {
+	return e.id
 }
 
-func (e *TransactionCreated) GetDateTime() time.Time {
-	return time.Now()
+func (e *TransactionCreated) GetName() string {
+	return "TransactionCreated"
 }
 
 func (e *TransactionCreated) GetPayload() interface{} {
-	return e.Payload
+	return e.payload
 }
 
-func (e *TransactionCreated) SetPayload(payload interface{}) {
-	e.Payload = payload
+func (e *TransactionCreated) GetDateTime() time.Time {
+	return e.dateTime
 }
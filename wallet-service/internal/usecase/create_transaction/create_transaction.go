@@ -2,12 +2,19 @@ package createtransaction
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 	"wallet/internal/entity"
 	"wallet/internal/gateway"
 	"wallet/pkg/events"
 	"wallet/pkg/uow"
 )
 
+// ErrEventsNotPublished is returned along with the output when a transaction
+// was committed but some of its events could not be published.
+var ErrEventsNotPublished = errors.New("events not published")
+
 type CreateTransactionInputDTO struct {
 	AccountIdFrom string  `json:"account_id_from"`
 	AccountIdTo   string  `json:"account_id_to"`
@@ -15,52 +22,71 @@ type CreateTransactionInputDTO struct {
 }
 
 type CreateTransactionOutputDTO struct {
-	Id            string  `json:"id"`
-	AccountIdFrom string  `json:"account_id_from"`
-	AccountIdTo   string  `json:"account_id_to"`
-	Amount        float64 `json:"amount"`
+	Id            string    `json:"id"`
+	AccountIdFrom string    `json:"account_id_from"`
+	AccountIdTo   string    `json:"account_id_to"`
+	Amount        float64   `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PartitionKey keys transaction events by the debited account.
+func (o *CreateTransactionOutputDTO) PartitionKey() string {
+	return o.AccountIdFrom
 }
 
+// BalanceUpdatedOutputDTO is the new balance of one account; a transfer
+// emits one per account so each is ordered on its account's partition.
 type BalanceUpdatedOutputDTO struct {
-	AccountIdFrom        string  `json:"account_id_from"`
-	AccountIdTo          string  `json:"account_id_to"`
-	BalanceAccountIdFrom float64 `json:"balance_account_id_from"`
-	BalanceAccountIdTo   float64 `json:"balance_account_id_to"`
+	AccountId     string    `json:"account_id"`
+	Balance       float64   `json:"balance"`
+	Sequence      int64     `json:"sequence"`
+	TransactionId string    `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PartitionKey keys balance events by account.
+func (o *BalanceUpdatedOutputDTO) PartitionKey() string {
+	return o.AccountId
 }
 
+// CreateTransactionUseCase is shared by all requests; it creates new events
+// for every transfer from the factories.
 type CreateTransactionUseCase struct {
-	Uow                     uow.UowInterface
-	EventDispatcher         events.EventDispatcherInterface
-	TransactionCreatedEvent events.EventInterface
-	BalanceUpdatedEvent     events.EventInterface
+	Uow                   uow.UowInterface
+	EventDispatcher       events.EventDispatcherInterface
+	NewTransactionCreated events.EventFactory
+	NewBalanceUpdated     events.EventFactory
 }
 
 func NewCreateTransactionUseCase(
 	uow uow.UowInterface,
 	eventsDispatcher events.EventDispatcherInterface,
-	transactionCreated events.EventInterface,
-	balanceUpdated events.EventInterface,
+	newTransactionCreated events.EventFactory,
+	newBalanceUpdated events.EventFactory,
 ) *CreateTransactionUseCase {
 	return &CreateTransactionUseCase{
-		Uow:                     uow,
-		EventDispatcher:         eventsDispatcher,
-		TransactionCreatedEvent: transactionCreated,
-		BalanceUpdatedEvent:     balanceUpdated,
+		Uow:                   uow,
+		EventDispatcher:       eventsDispatcher,
+		NewTransactionCreated: newTransactionCreated,
+		NewBalanceUpdated:     newBalanceUpdated,
 	}
 }
 
 func (uc *CreateTransactionUseCase) Execute(ctx context.Context, input CreateTransactionInputDTO) (*CreateTransactionOutputDTO, error) {
 	var transactionOutput *CreateTransactionOutputDTO
-	var balanceOutput *BalanceUpdatedOutputDTO
+	var balanceOutputs []*BalanceUpdatedOutputDTO
+
+	err := uc.Uow.Do(ctx, func(ctx context.Context) error {
+		// Start over when the unit of work is retried
+		balanceOutputs = nil
 
-	err := uc.Uow.Do(ctx, func(uow *uow.Uow) error {
 		// Get repositories
-		accountGateway, err := uc.getAccountRepository(ctx)
+		accountGateway, err := uow.Get[gateway.AccountGateway](ctx, uc.Uow)
 		if err != nil {
 			return err
 		}
 
-		transactionGateway, err := uc.getTransactionRepository(ctx)
+		transactionGateway, err := uow.Get[gateway.TransactionGateway](ctx, uc.Uow)
 		if err != nil {
 			return err
 		}
@@ -99,11 +125,14 @@ func (uc *CreateTransactionUseCase) Execute(ctx context.Context, input CreateTra
 			return err
 		}
 
-		balanceOutput = &BalanceUpdatedOutputDTO{
-			AccountIdFrom:        accountFrom.Id,
-			AccountIdTo:          accountTo.Id,
-			BalanceAccountIdFrom: accountFrom.Balance,
-			BalanceAccountIdTo:   accountTo.Balance,
+		for _, account := range []*entity.Account{accountFrom, accountTo} {
+			balanceOutputs = append(balanceOutputs, &BalanceUpdatedOutputDTO{
+				AccountId:     account.Id,
+				Balance:       account.Balance,
+				Sequence:      account.Sequence,
+				TransactionId: transaction.Id,
+				CreatedAt:     transaction.CreatedAt,
+			})
 		}
 
 		transactionOutput = &CreateTransactionOutputDTO{
@@ -111,6 +140,7 @@ func (uc *CreateTransactionUseCase) Execute(ctx context.Context, input CreateTra
 			AccountIdFrom: transaction.AccountFrom.Id,
 			AccountIdTo:   transaction.AccountTo.Id,
 			Amount:        transaction.Amount,
+			CreatedAt:     transaction.CreatedAt,
 		}
 
 		return nil
@@ -120,27 +150,16 @@ func (uc *CreateTransactionUseCase) Execute(ctx context.Context, input CreateTra
 		return nil, err
 	}
 
-	// Dispatch the event after the transaction is successfully committed
-	uc.TransactionCreatedEvent.SetPayload(transactionOutput)
-	uc.EventDispatcher.Dispatch(uc.TransactionCreatedEvent)
+	// Dispatch the event after the transaction is successfully committed. The
+	// transaction stands even if the caller goes away, so publishing does too.
+	ctx = context.WithoutCancel(ctx)
+	errs := []error{uc.EventDispatcher.Dispatch(ctx, uc.NewTransactionCreated(transactionOutput))}
 
-	uc.BalanceUpdatedEvent.SetPayload(balanceOutput)
-	uc.EventDispatcher.Dispatch(uc.BalanceUpdatedEvent)
-	return transactionOutput, nil
-}
-
-func (uc *CreateTransactionUseCase) getAccountRepository(ctx context.Context) (gateway.AccountGateway, error) {
-	accountRepository, err := uc.Uow.GetRepository(ctx, "AccountRepository")
-	if err != nil {
-		return nil, err
+	for _, balanceOutput := range balanceOutputs {
+		errs = append(errs, uc.EventDispatcher.Dispatch(ctx, uc.NewBalanceUpdated(balanceOutput)))
 	}
-	return accountRepository.(gateway.AccountGateway), nil
-}
-
-func (uc *CreateTransactionUseCase) getTransactionRepository(ctx context.Context) (gateway.TransactionGateway, error) {
-	transactionRepository, err := uc.Uow.GetRepository(ctx, "TransactionRepository")
-	if err != nil {
-		return nil, err
+	if err := errors.Join(errs...); err != nil {
+		return transactionOutput, fmt.Errorf("%w for transaction %s: %w", ErrEventsNotPublished, transactionOutput.Id, err)
 	}
-	return transactionRepository.(gateway.TransactionGateway), nil
+	return transactionOutput, nil
 }
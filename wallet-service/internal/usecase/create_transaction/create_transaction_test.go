@@ -3,14 +3,31 @@ package createtransaction
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"wallet/internal/entity"
+	"wallet/internal/event"
+	"wallet/internal/gateway"
 	"wallet/internal/usecase/mocks"
+	"wallet/pkg/events"
+	"wallet/pkg/uow"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+var (
+	newTransactionCreated = events.NewFactory(event.NewTransactionCreated)
+	newBalanceUpdated     = events.NewFactory(event.NewBalanceUpdated)
+)
+
+func eventNamed(name string) interface{} {
+	return mock.MatchedBy(func(e events.EventInterface) bool {
+		return e.GetName() == name
+	})
+}
+
 func TestCreateTransactionUseCase_Execute(t *testing.T) {
 	client1, _ := entity.NewClient("John", "john@example.com")
 	account1, _ := entity.NewAccount(client1)
@@ -29,20 +46,14 @@ func TestCreateTransactionUseCase_Execute(t *testing.T) {
 	mockTransactionGateway.On("Create", mock.Anything).Return(nil)
 
 	mockUow := &mocks.UowMock{}
-	mockUow.On("GetRepository", mock.Anything, "AccountRepository").Return(mockAccountGateway, nil)
-	mockUow.On("GetRepository", mock.Anything, "TransactionRepository").Return(mockTransactionGateway, nil)
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.AccountGateway]()).Return(mockAccountGateway, nil)
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.TransactionGateway]()).Return(mockTransactionGateway, nil)
 	mockUow.On("Do", mock.Anything, mock.Anything).Return(nil)
 
 	mockEventDispatcher := &mocks.EventDispatcher{}
 	mockEventDispatcher.On("Dispatch", mock.Anything).Return(nil)
 
-	mockEvent := &mocks.Event{}
-	mockEvent.On("SetPayload", mock.Anything).Return()
-
-	mockEvent2 := &mocks.Event{}
-	mockEvent2.On("SetPayload", mock.Anything).Return()
-
-	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, mockEvent, mockEvent2)
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
 
 	input := CreateTransactionInputDTO{
 		AccountIdFrom: "account1",
@@ -56,8 +67,50 @@ func TestCreateTransactionUseCase_Execute(t *testing.T) {
 	assert.NotNil(t, output)
 	mockUow.AssertExpectations(t)
 	mockEventDispatcher.AssertExpectations(t)
-	mockEvent.AssertCalled(t, "SetPayload", mock.Anything)
-	mockEventDispatcher.AssertCalled(t, "Dispatch", mockEvent)
+	mockEventDispatcher.AssertCalled(t, "Dispatch", eventNamed("TransactionCreated"))
+}
+
+func TestCreateTransactionUseCase_DispatchFailed(t *testing.T) {
+	client1, _ := entity.NewClient("John", "john@example.com")
+	account1, _ := entity.NewAccount(client1)
+	account1.Credit(100)
+
+	client2, _ := entity.NewClient("Jane", "jane@example.com")
+	account2, _ := entity.NewAccount(client2)
+
+	mockAccountGateway := &mocks.AccountGateway{}
+	mockAccountGateway.On("FindById", "account1").Return(account1, nil)
+	mockAccountGateway.On("FindById", "account2").Return(account2, nil)
+	mockAccountGateway.On("UpdateBalance", account1).Return(nil)
+	mockAccountGateway.On("UpdateBalance", account2).Return(nil)
+
+	mockTransactionGateway := &mocks.TransactionGateway{}
+	mockTransactionGateway.On("Create", mock.Anything).Return(nil)
+
+	mockUow := &mocks.UowMock{}
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.AccountGateway]()).Return(mockAccountGateway, nil)
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.TransactionGateway]()).Return(mockTransactionGateway, nil)
+	mockUow.On("Do", mock.Anything, mock.Anything).Return(nil)
+
+	mockEventDispatcher := &mocks.EventDispatcher{}
+	mockEventDispatcher.On("Dispatch", eventNamed("TransactionCreated")).Return(errors.New("broker unavailable"))
+	mockEventDispatcher.On("Dispatch", eventNamed("BalanceUpdated")).Return(nil)
+
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
+
+	input := CreateTransactionInputDTO{
+		AccountIdFrom: "account1",
+		AccountIdTo:   "account2",
+		Amount:        50,
+	}
+
+	output, err := useCase.Execute(context.Background(), input)
+
+	assert.ErrorIs(t, err, ErrEventsNotPublished)
+	assert.ErrorContains(t, err, "broker unavailable")
+	assert.NotNil(t, output)
+	// The balance events are still dispatched
+	mockEventDispatcher.AssertNumberOfCalls(t, "Dispatch", 3)
 }
 
 func TestCreateTransactionUseCase_FailGetAccountRepository(t *testing.T) {
@@ -65,10 +118,8 @@ func TestCreateTransactionUseCase_FailGetAccountRepository(t *testing.T) {
 	mockUow.On("Do", mock.Anything, mock.Anything).Return(errors.New("error getting repository"))
 
 	mockEventDispatcher := &mocks.EventDispatcher{}
-	mockEvent := &mocks.Event{}
-	mockEvent2 := &mocks.Event{}
 
-	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, mockEvent, mockEvent2)
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
 
 	input := CreateTransactionInputDTO{
 		AccountIdFrom: "account1",
@@ -83,7 +134,6 @@ func TestCreateTransactionUseCase_FailGetAccountRepository(t *testing.T) {
 	assert.Equal(t, "error getting repository", err.Error())
 	mockUow.AssertExpectations(t)
 	mockEventDispatcher.AssertNotCalled(t, "Dispatch", mock.Anything)
-	mockEvent.AssertNotCalled(t, "SetPayload", mock.Anything)
 }
 
 func TestCreateTransactionUseCase_FailGetTransactionRepository(t *testing.T) {
@@ -92,10 +142,8 @@ func TestCreateTransactionUseCase_FailGetTransactionRepository(t *testing.T) {
 	mockUow.On("Do", mock.Anything, mock.Anything).Return(errors.New("error getting transaction repository"))
 
 	mockEventDispatcher := &mocks.EventDispatcher{}
-	mockEvent := &mocks.Event{}
-	mockEvent2 := &mocks.Event{}
 
-	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, mockEvent, mockEvent2)
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
 
 	input := CreateTransactionInputDTO{
 		AccountIdFrom: "account1",
@@ -120,9 +168,7 @@ func TestCreateTransactionUseCase_AccountFromNotFound(t *testing.T) {
 	mockUow.On("Do", mock.Anything, mock.Anything).Return(errors.New("account not found"))
 
 	mockEventDispatcher := &mocks.EventDispatcher{}
-	mockEvent := &mocks.Event{}
-	mockEvent2 := &mocks.Event{}
-	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, mockEvent, mockEvent2)
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
 
 	input := CreateTransactionInputDTO{
 		AccountIdFrom: "account1",
@@ -151,9 +197,7 @@ func TestCreateTransactionUseCase_AccountToNotFound(t *testing.T) {
 	mockUow.On("Do", mock.Anything, mock.Anything).Return(errors.New("account not found"))
 
 	mockEventDispatcher := &mocks.EventDispatcher{}
-	mockEvent := &mocks.Event{}
-	mockEvent2 := &mocks.Event{}
-	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, mockEvent, mockEvent2)
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
 
 	input := CreateTransactionInputDTO{
 		AccountIdFrom: "account1",
@@ -186,9 +230,7 @@ func TestCreateTransactionUseCase_TransactionCreationFailed(t *testing.T) {
 	mockUow.On("Do", mock.Anything, mock.Anything).Return(errors.New(entity.ErrInvalidTransaction))
 
 	mockEventDispatcher := &mocks.EventDispatcher{}
-	mockEvent := &mocks.Event{}
-	mockEvent2 := &mocks.Event{}
-	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, mockEvent, mockEvent2)
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
 
 	input := CreateTransactionInputDTO{
 		AccountIdFrom: "account1",
@@ -204,3 +246,150 @@ func TestCreateTransactionUseCase_TransactionCreationFailed(t *testing.T) {
 	mockUow.AssertExpectations(t)
 	mockEventDispatcher.AssertNotCalled(t, "Dispatch", mock.Anything)
 }
+
+func TestCreateTransactionUseCase_SelfTransfer(t *testing.T) {
+	client1, _ := entity.NewClient("John", "john@example.com")
+	account1, _ := entity.NewAccount(client1)
+	account1.Credit(100)
+	loadedAgain := *account1
+
+	// Every lookup returns a new instance, as the database does
+	mockAccountGateway := &mocks.AccountGateway{}
+	mockAccountGateway.On("FindById", "account1").Return(account1, nil).Once()
+	mockAccountGateway.On("FindById", "account1").Return(&loadedAgain, nil).Once()
+
+	mockUow := &mocks.UowMock{}
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.AccountGateway]()).Return(mockAccountGateway, nil)
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.TransactionGateway]()).Return(&mocks.TransactionGateway{}, nil)
+	mockUow.On("Do", mock.Anything, mock.Anything).Return(nil)
+
+	mockEventDispatcher := &mocks.EventDispatcher{}
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
+
+	input := CreateTransactionInputDTO{
+		AccountIdFrom: "account1",
+		AccountIdTo:   "account1",
+		Amount:        50,
+	}
+
+	output, err := useCase.Execute(context.Background(), input)
+
+	assert.Nil(t, output)
+	assert.EqualError(t, err, entity.ErrInvalidTransaction)
+	mockAccountGateway.AssertNotCalled(t, "UpdateBalance", mock.Anything)
+	mockEventDispatcher.AssertNotCalled(t, "Dispatch", mock.Anything)
+}
+
+func TestCreateTransactionUseCase_BalanceUpdatedPerAccount(t *testing.T) {
+	client1, _ := entity.NewClient("John", "john@example.com")
+	account1, _ := entity.NewAccount(client1)
+	account1.Credit(100)
+
+	client2, _ := entity.NewClient("Jane", "jane@example.com")
+	account2, _ := entity.NewAccount(client2)
+
+	mockAccountGateway := &mocks.AccountGateway{}
+	mockAccountGateway.On("FindById", "account1").Return(account1, nil)
+	mockAccountGateway.On("FindById", "account2").Return(account2, nil)
+	mockAccountGateway.On("UpdateBalance", mock.Anything).Return(nil)
+
+	mockTransactionGateway := &mocks.TransactionGateway{}
+	mockTransactionGateway.On("Create", mock.Anything).Return(nil)
+
+	mockUow := &mocks.UowMock{}
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.AccountGateway]()).Return(mockAccountGateway, nil)
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.TransactionGateway]()).Return(mockTransactionGateway, nil)
+	mockUow.On("Do", mock.Anything, mock.Anything).Return(nil)
+
+	balanceUpdated := func(matches func(payload *BalanceUpdatedOutputDTO) bool) interface{} {
+		return mock.MatchedBy(func(e events.EventInterface) bool {
+			payload, ok := e.GetPayload().(*BalanceUpdatedOutputDTO)
+			return ok && e.GetName() == "BalanceUpdated" && matches(payload)
+		})
+	}
+	mockEventDispatcher := &mocks.EventDispatcher{}
+	mockEventDispatcher.On("Dispatch", eventNamed("TransactionCreated")).Return(nil).Once()
+	mockEventDispatcher.On("Dispatch", balanceUpdated(func(payload *BalanceUpdatedOutputDTO) bool {
+		return payload.AccountId == account1.Id && payload.Balance == 50 && payload.Sequence == 2 &&
+			payload.PartitionKey() == account1.Id
+	})).Return(nil).Once()
+	mockEventDispatcher.On("Dispatch", balanceUpdated(func(payload *BalanceUpdatedOutputDTO) bool {
+		return payload.AccountId == account2.Id && payload.Balance == 50 && payload.Sequence == 1 &&
+			payload.PartitionKey() == account2.Id
+	})).Return(nil).Once()
+
+	useCase := NewCreateTransactionUseCase(mockUow, mockEventDispatcher, newTransactionCreated, newBalanceUpdated)
+
+	input := CreateTransactionInputDTO{
+		AccountIdFrom: "account1",
+		AccountIdTo:   "account2",
+		Amount:        50,
+	}
+
+	_, err := useCase.Execute(context.Background(), input)
+
+	assert.Nil(t, err)
+	mockEventDispatcher.AssertExpectations(t)
+	mockEventDispatcher.AssertNumberOfCalls(t, "Dispatch", 3)
+}
+
+// Run with -race: concurrent transfers share the use case and must each
+// publish their own payloads.
+func TestCreateTransactionUseCase_ConcurrentTransfers(t *testing.T) {
+	const transfers = 50
+
+	mockAccountGateway := &mocks.AccountGateway{}
+	for i := 0; i < transfers; i++ {
+		client, _ := entity.NewClient("John", "john@example.com")
+		from, _ := entity.NewAccount(client)
+		from.Credit(100)
+		to, _ := entity.NewAccount(client)
+		mockAccountGateway.On("FindById", fmt.Sprintf("from%d", i)).Return(from, nil)
+		mockAccountGateway.On("FindById", fmt.Sprintf("to%d", i)).Return(to, nil)
+	}
+	mockAccountGateway.On("UpdateBalance", mock.Anything).Return(nil)
+
+	mockTransactionGateway := &mocks.TransactionGateway{}
+	mockTransactionGateway.On("Create", mock.Anything).Return(nil)
+
+	mockUow := &mocks.UowMock{}
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.AccountGateway]()).Return(mockAccountGateway, nil)
+	mockUow.On("GetRepository", mock.Anything, uow.RepositoryName[gateway.TransactionGateway]()).Return(mockTransactionGateway, nil)
+	mockUow.On("Do", mock.Anything, mock.Anything).Return(nil)
+
+	mu := sync.Mutex{}
+	published := map[string]string{}
+	record := events.HandlerFunc(func(ctx context.Context, e events.EventInterface) error {
+		payload := e.GetPayload().(*CreateTransactionOutputDTO)
+		mu.Lock()
+		defer mu.Unlock()
+		published[payload.Id] = payload.AccountIdFrom
+		return nil
+	})
+	dispatcher := events.NewEventDispatcher()
+	dispatcher.Register("TransactionCreated", &record)
+
+	useCase := NewCreateTransactionUseCase(mockUow, dispatcher, newTransactionCreated, newBalanceUpdated)
+
+	wg := sync.WaitGroup{}
+	outputs := make([]*CreateTransactionOutputDTO, transfers)
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := useCase.Execute(context.Background(), CreateTransactionInputDTO{
+				AccountIdFrom: fmt.Sprintf("from%d", i),
+				AccountIdTo:   fmt.Sprintf("to%d", i),
+				Amount:        10,
+			})
+			assert.Nil(t, err)
+			outputs[i] = output
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, published, transfers)
+	for _, output := range outputs {
+		assert.Equal(t, output.AccountIdFrom, published[output.Id])
+	}
+}
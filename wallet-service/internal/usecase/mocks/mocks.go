@@ -69,25 +69,15 @@ func (m *UowMock) GetRepository(ctx context.Context, name string) (interface{},
 	return args.Get(0), args.Error(1)
 }
 
-func (m *UowMock) Do(ctx context.Context, fn func(uow *uow.Uow) error) error {
+func (m *UowMock) Do(ctx context.Context, fn func(ctx context.Context) error) error {
 	args := m.Called(ctx, fn)
-	// Execute the function with nil to simulate UOW behavior
+	// Execute the function to simulate UOW behavior
 	if args.Get(0) == nil {
-		_ = fn(nil)
+		return fn(ctx)
 	}
 	return args.Error(0)
 }
 
-func (m *UowMock) CommitOrRollback() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-func (m *UowMock) Rollback() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
 func (m *UowMock) UnRegister(name string) {
 	m.Called(name)
 }
@@ -98,12 +88,12 @@ type EventDispatcher struct {
 	mock.Mock
 }
 
-func (m *EventDispatcher) Register(eventName string, handler events.EventHandlerInterface) error {
+func (m *EventDispatcher) Register(eventName string, handler events.EventHandlerInterface, middleware ...events.Middleware) error {
 	args := m.Called(eventName, handler)
 	return args.Error(0)
 }
 
-func (m *EventDispatcher) Dispatch(event events.EventInterface) error {
+func (m *EventDispatcher) Dispatch(ctx context.Context, event events.EventInterface) error {
 	args := m.Called(event)
 	return args.Error(0)
 }
@@ -126,6 +116,11 @@ type Event struct {
 	mock.Mock
 }
 
+func (m *Event) GetID() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 func (m *Event) GetName() string {
 	args := m.Called()
 	return args.String(0)
@@ -140,7 +135,3 @@ func (m *Event) GetPayload() interface{} {
 	args := m.Called()
 	return args.Get(0)
 }
-
-func (m *Event) SetPayload(payload interface{}) {
-	m.Called(payload)
-}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	createaccount "wallet/internal/usecase/create_account"
 )
@@ -29,11 +30,10 @@ func (h *WebAccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// The status goes out with the first byte of the body
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(output); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		log.Printf("Writing response: %v", err)
 	}
-
-	w.WriteHeader(http.StatusCreated)
 }
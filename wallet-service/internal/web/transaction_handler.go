@@ -2,7 +2,10 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"wallet/internal/entity"
 	createtransaction "wallet/internal/usecase/create_transaction"
 )
 
@@ -24,17 +27,37 @@ func (h *WebTransactionHandler) CreateTransaction(w http.ResponseWriter, r *http
 	}
 
 	output, err := h.CreateTransactionUseCase.Execute(r.Context(), input)
+	if errors.Is(err, createtransaction.ErrEventsNotPublished) {
+		// The transfer is committed, so it is still reported as created
+		log.Print(err)
+		err = nil
+	}
+	if isInvalidTransfer(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// The status goes out with the first byte of the body
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(output); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		log.Printf("Writing response: %v", err)
 	}
+}
 
-	w.WriteHeader(http.StatusCreated)
-
+// isInvalidTransfer reports whether err is a transfer the accounts refused,
+// which retrying the same request cannot fix.
+func isInvalidTransfer(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch err.Error() {
+	case entity.ErrInvalidTransaction, entity.ErrInvalidAccount, entity.ErrInvalidAmount, entity.ErrNotEnoughBalance:
+		return true
+	}
+	return false
 }
@@ -1,8 +1,10 @@
 package webserver
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
@@ -12,6 +14,10 @@ type WebServer struct {
 	Router        chi.Router
 	Handlers      map[string]http.HandlerFunc
 	WebServerPort string
+
+	mu     sync.Mutex
+	server *http.Server
+	closed bool
 }
 
 func NewWebServer(port string) *WebServer {
@@ -26,14 +32,41 @@ func (ws *WebServer) AddHandler(path string, handler http.HandlerFunc) {
 	ws.Handlers[path] = handler
 }
 
-func (ws *WebServer) Start() error {
+// Handler registers the added handlers on the router and returns it. Call it
+// once, after adding every handler.
+func (ws *WebServer) Handler() http.Handler {
 	ws.Router.Use(middleware.Logger)
+	for path, handler := range ws.Handlers {
+		ws.Router.Post(path, handler)
+	}
+	return ws.Router
+}
 
+// Start serves the handlers on WebServerPort until Shutdown, after which it
+// returns http.ErrServerClosed.
+func (ws *WebServer) Start() error {
 	log.Println("Starting web server on port", ws.WebServerPort)
 
-	for path, handler := range ws.Handlers {
-		ws.Router.Post(path, handler)
+	ws.mu.Lock()
+	if ws.closed {
+		ws.mu.Unlock()
+		return http.ErrServerClosed
 	}
+	ws.server = &http.Server{Addr: ws.WebServerPort, Handler: ws.Handler()}
+	server := ws.server
+	ws.mu.Unlock()
+	return server.ListenAndServe()
+}
 
-	return http.ListenAndServe(ws.WebServerPort, ws.Router)
+// Shutdown stops accepting connections and waits for the requests in flight
+// to finish, or for ctx to be done. A later Start returns at once.
+func (ws *WebServer) Shutdown(ctx context.Context) error {
+	ws.mu.Lock()
+	ws.closed = true
+	server := ws.server
+	ws.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type balancePayload struct {
+	AccountId     string    `json:"account_id"`
+	Balance       float64   `json:"balance"`
+	Sequence      int64     `json:"sequence"`
+	TransactionId string    `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func TestCodecFor(t *testing.T) {
+	for contentType, expected := range map[string]Codec{
+		"":                     JSONCodec{},
+		"application/json":     JSONCodec{},
+		"application/protobuf": ProtobufCodec{},
+	} {
+		codec, err := CodecFor(contentType)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, codec)
+	}
+
+	_, err := CodecFor("application/xml")
+	assert.EqualError(t, err, `unsupported content type "application/xml"`)
+}
+
+// protoSchema returns the fields of every message of schemas/events.v1.proto
+// by name, as "<type> <name> = <number>".
+func protoSchema(t *testing.T) map[string][]string {
+	data, err := os.ReadFile("schemas/events.v1.proto")
+	require.Nil(t, err)
+	messages := map[string][]string{}
+	message := ""
+	field := regexp.MustCompile(`^\s*([\w.]+) (\w+) = (\d+);`)
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "message ") {
+			message = strings.Fields(line)[1]
+			continue
+		}
+		if match := field.FindStringSubmatch(line); match != nil && message != "" {
+			messages[message] = append(messages[message], fmt.Sprintf("%s %s = %s", match[1], match[2], match[3]))
+		}
+	}
+	return messages
+}
+
+func TestProtoMessagesMatchSchema(t *testing.T) {
+	kinds := map[protoKind]string{
+		protoString:    "string",
+		protoMoney:     "string",
+		protoInt64:     "int64",
+		protoTimestamp: "google.protobuf.Timestamp",
+	}
+	schema := protoSchema(t)
+
+	envelope := []string{fmt.Sprintf("string name = %d", protoEventName)}
+	for name, message := range protoMessages {
+		var fields []string
+		for _, field := range message.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s = %d", kinds[field.Kind], field.Name, field.Number))
+		}
+		assert.ElementsMatch(t, schema[name], fields, name)
+
+		payload := regexp.MustCompile(`(.)([A-Z])`).ReplaceAllString(name, "${1}_${2}")
+		envelope = append(envelope, fmt.Sprintf("%s %s = %d", name, strings.ToLower(payload), message.Number))
+	}
+	assert.ElementsMatch(t, schema["Event"], envelope, "Event")
+	assert.Len(t, schema, len(protoMessages)+1, "every message of the schema has an entry")
+}
+
+func TestJSONCodec(t *testing.T) {
+	event := &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.5}}
+
+	data, err := JSONCodec{}.Marshal(event)
+	require.Nil(t, err)
+	name, payload, err := JSONCodec{}.Unmarshal(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "BalanceUpdated", name)
+	assert.Equal(t, map[string]interface{}{"account_id": "a1", "balance": 90.5}, payload)
+}
+
+func TestProtobufCodec(t *testing.T) {
+	t.Run("round-trips an event with exact decimals and timestamps", func(t *testing.T) {
+		createdAt := time.Date(2025, 3, 3, 10, 0, 0, 123456789, time.UTC)
+		event := &testEvent{Name: "BalanceUpdated", Payload: balancePayload{
+			AccountId:     "a1",
+			Balance:       0.1,
+			Sequence:      7,
+			TransactionId: "tx1",
+			CreatedAt:     createdAt,
+		}}
+
+		data, err := ProtobufCodec{}.Marshal(event)
+		require.Nil(t, err)
+		name, payload, err := ProtobufCodec{}.Unmarshal(data)
+		require.Nil(t, err)
+
+		assert.Equal(t, "BalanceUpdated", name)
+		assert.Equal(t, map[string]interface{}{
+			"account_id":     "a1",
+			"balance":        json.Number("0.10"),
+			"sequence":       json.Number("7"),
+			"transaction_id": "tx1",
+			"created_at":     "2025-03-03T10:00:00.123456789Z",
+		}, payload)
+
+		var decoded balancePayload
+		raw, _ := json.Marshal(payload)
+		require.Nil(t, json.Unmarshal(raw, &decoded))
+		assert.Equal(t, event.Payload, decoded)
+	})
+
+	t.Run("keeps zero balances", func(t *testing.T) {
+		event := &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 0}}
+
+		data, err := ProtobufCodec{}.Marshal(event)
+		require.Nil(t, err)
+		_, payload, err := ProtobufCodec{}.Unmarshal(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]interface{}{"account_id": "a1", "balance": json.Number("0.00")}, payload)
+	})
+
+	t.Run("rounds money to minor units", func(t *testing.T) {
+		event := &testEvent{Name: "TransactionCreated", Payload: map[string]interface{}{"id": "tx1", "amount": 0.1 + 0.2}}
+
+		data, err := ProtobufCodec{}.Marshal(event)
+		require.Nil(t, err)
+		_, payload, err := ProtobufCodec{}.Unmarshal(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]interface{}{"id": "tx1", "amount": json.Number("0.30")}, payload)
+	})
+
+	t.Run("skips fields added by newer producers", func(t *testing.T) {
+		payload := protowire.AppendTag(nil, 1, protowire.BytesType)
+		payload = protowire.AppendString(payload, "tx1")
+		payload = protowire.AppendTag(payload, 99, protowire.VarintType)
+		payload = protowire.AppendVarint(payload, 1)
+		data := protowire.AppendTag(nil, 1, protowire.BytesType)
+		data = protowire.AppendString(data, "TransactionCreated")
+		data = protowire.AppendTag(data, 10, protowire.BytesType)
+		data = protowire.AppendBytes(data, payload)
+
+		name, decoded, err := ProtobufCodec{}.Unmarshal(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "TransactionCreated", name)
+		assert.Equal(t, map[string]interface{}{"id": "tx1"}, decoded)
+	})
+
+	t.Run("rejects payload fields without a protobuf field", func(t *testing.T) {
+		event := &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "currency": "BRL"}}
+
+		_, err := ProtobufCodec{}.Marshal(event)
+
+		assert.EqualError(t, err, "BalanceUpdated has no protobuf field for currency")
+	})
+
+	t.Run("refuses the legacy per-transfer balance update", func(t *testing.T) {
+		event := &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{
+			"account_id_from":         "a1",
+			"account_id_to":           "a2",
+			"balance_account_id_from": 90,
+			"balance_account_id_to":   110,
+		}}
+
+		_, err := ProtobufCodec{}.Marshal(event)
+
+		assert.EqualError(t, err, "BalanceUpdated has no protobuf field for account_id_from, account_id_to, balance_account_id_from, balance_account_id_to")
+	})
+
+	t.Run("rejects unknown events and malformed data", func(t *testing.T) {
+		_, err := ProtobufCodec{}.Marshal(&testEvent{Name: "AccountClosed"})
+		assert.EqualError(t, err, `no protobuf message for event "AccountClosed"`)
+
+		_, _, err = ProtobufCodec{}.Unmarshal([]byte{0xff})
+		assert.NotNil(t, err)
+	})
+}
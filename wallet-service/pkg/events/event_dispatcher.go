@@ -1,35 +1,123 @@
 package events
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 )
 
 var ErrHandlerAlreadyRegistered = errors.New("handler already registered")
 
+// EventDispatcher runs the handlers registered for an event. It is safe for
+// concurrent use.
+//
+// Handlers are registered for an event name or a pattern: "*" matches every
+// event and a trailing "*" matches names with that prefix, e.g. "Balance*".
 type EventDispatcher struct {
+	mu       sync.RWMutex
 	handlers map[string][]EventHandlerInterface
+	// registrations holds every registered handler behind its middleware.
+	registrations map[handlerKey]registration
+	next          int
+}
+
+type handlerKey struct {
+	pattern string
+	handler EventHandlerInterface
+}
+
+type registration struct {
+	handler  EventHandlerInterface
+	wrapped  EventHandlerInterface
+	priority int
+	// order breaks ties between handlers of the same priority.
+	order int
 }
 
 func NewEventDispatcher() *EventDispatcher {
 	return &EventDispatcher{
-		handlers: make(map[string][]EventHandlerInterface),
+		handlers:      make(map[string][]EventHandlerInterface),
+		registrations: make(map[handlerKey]registration),
 	}
 }
 
-func (ev *EventDispatcher) Dispatch(event EventInterface) error {
-	if handlers, ok := ev.handlers[event.GetName()]; ok {
+// Dispatch runs the handlers of event and waits for them. Handlers run by
+// priority, highest first: all handlers of one priority run concurrently and
+// the next priority starts once they are done. It returns the errors of every
+// failed handler joined together; a panicking handler fails with
+// ErrHandlerPanicked instead of crashing the service.
+func (ed *EventDispatcher) Dispatch(ctx context.Context, event EventInterface) error {
+	var errs []error
+	for _, level := range ed.matching(event.GetName()) {
+		levelErrs := make([]error, len(level))
 		wg := &sync.WaitGroup{}
-		for _, handler := range handlers {
+		for i, r := range level {
 			wg.Add(1)
-			go handler.Handle(event, wg)
+			go func() {
+				defer wg.Done()
+				if err := Recover()(r.wrapped).Handle(ctx, event); err != nil {
+					levelErrs[i] = fmt.Errorf("%s handler %T: %w", event.GetName(), r.handler, err)
+				}
+			}()
 		}
 		wg.Wait()
+		errs = append(errs, levelErrs...)
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// matching returns the registrations whose pattern matches eventName,
+// grouped by priority, highest first.
+func (ed *EventDispatcher) matching(eventName string) [][]registration {
+	ed.mu.RLock()
+	var matched []registration
+	for pattern, handlers := range ed.handlers {
+		if !matchEventName(pattern, eventName) {
+			continue
+		}
+		for _, handler := range handlers {
+			matched = append(matched, ed.registrations[handlerKey{pattern, handler}])
+		}
+	}
+	ed.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].priority != matched[j].priority {
+			return matched[i].priority > matched[j].priority
+		}
+		return matched[i].order < matched[j].order
+	})
+	var levels [][]registration
+	for i, r := range matched {
+		if i == 0 || r.priority != matched[i-1].priority {
+			levels = append(levels, nil)
+		}
+		levels[len(levels)-1] = append(levels[len(levels)-1], r)
+	}
+	return levels
 }
 
-func (ed *EventDispatcher) Register(eventName string, handler EventHandlerInterface) error {
+func matchEventName(pattern, eventName string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventName, prefix)
+	}
+	return pattern == eventName
+}
+
+// Register adds a handler for an event name or pattern with priority 0.
+// Middleware wraps only this handler, the first one being the outermost.
+func (ed *EventDispatcher) Register(eventName string, handler EventHandlerInterface, middleware ...Middleware) error {
+	return ed.RegisterWithPriority(eventName, 0, handler, middleware...)
+}
+
+// RegisterWithPriority adds a handler that runs before every handler of a
+// lower priority and after every handler of a higher one.
+func (ed *EventDispatcher) RegisterWithPriority(eventName string, priority int, handler EventHandlerInterface, middleware ...Middleware) error {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
 	if _, ok := ed.handlers[eventName]; ok {
 		for _, h := range ed.handlers[eventName] {
 			if h == handler {
@@ -38,10 +126,19 @@ func (ed *EventDispatcher) Register(eventName string, handler EventHandlerInterf
 		}
 	}
 	ed.handlers[eventName] = append(ed.handlers[eventName], handler)
+	ed.registrations[handlerKey{eventName, handler}] = registration{
+		handler:  handler,
+		wrapped:  Chain(middleware...)(handler),
+		priority: priority,
+		order:    ed.next,
+	}
+	ed.next++
 	return nil
 }
 
 func (ed *EventDispatcher) Has(eventName string, handler EventHandlerInterface) bool {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
 	if _, ok := ed.handlers[eventName]; ok {
 		for _, h := range ed.handlers[eventName] {
 			if h == handler {
@@ -53,10 +150,13 @@ func (ed *EventDispatcher) Has(eventName string, handler EventHandlerInterface)
 }
 
 func (ed *EventDispatcher) Remove(eventName string, handler EventHandlerInterface) error {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
 	if _, ok := ed.handlers[eventName]; ok {
 		for i, h := range ed.handlers[eventName] {
 			if h == handler {
 				ed.handlers[eventName] = append(ed.handlers[eventName][:i], ed.handlers[eventName][i+1:]...)
+				delete(ed.registrations, handlerKey{eventName, handler})
 				return nil
 			}
 		}
@@ -65,5 +165,8 @@ func (ed *EventDispatcher) Remove(eventName string, handler EventHandlerInterfac
 }
 
 func (ed *EventDispatcher) Clear() {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
 	ed.handlers = make(map[string][]EventHandlerInterface)
+	ed.registrations = make(map[handlerKey]registration)
 }
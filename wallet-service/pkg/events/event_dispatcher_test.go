@@ -1,6 +1,8 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -15,6 +17,10 @@ type TestEvent struct {
 	Payload interface{}
 }
 
+func (e *TestEvent) GetID() string {
+	return "test-id"
+}
+
 func (e *TestEvent) GetName() string {
 	return e.Name
 }
@@ -27,15 +33,16 @@ func (e *TestEvent) GetDateTime() time.Time {
 	return time.Now()
 }
 
-func (e *TestEvent) SetPayload(payload interface{}) {
-	e.Payload = payload
+func NewTestEvent(payload interface{}) *TestEvent {
+	return &TestEvent{Name: "test", Payload: payload}
 }
 
 type TestEventHandler struct {
 	ID int
 }
 
-func (h *TestEventHandler) Handle(event EventInterface, wg *sync.WaitGroup) {
+func (h *TestEventHandler) Handle(ctx context.Context, event EventInterface) error {
+	return nil
 }
 
 type EventDispatcherTestSuite struct {
@@ -150,28 +157,158 @@ type MockHandler struct {
 	mock.Mock
 }
 
-func (m *MockHandler) Handle(event EventInterface, wg *sync.WaitGroup) {
-	m.Called(event)
-	wg.Done()
+func (m *MockHandler) Handle(ctx context.Context, event EventInterface) error {
+	args := m.Called(event)
+	return args.Error(0)
 }
 
 func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch() {
 	eh := &MockHandler{}
-	eh.On("Handle", &suite.event)
+	eh.On("Handle", &suite.event).Return(nil)
 
 	eh2 := &MockHandler{}
-	eh2.On("Handle", &suite.event)
+	eh2.On("Handle", &suite.event).Return(nil)
 
 	suite.eventDispatcher.Register(suite.event.GetName(), eh)
 	suite.eventDispatcher.Register(suite.event.GetName(), eh2)
 
-	suite.eventDispatcher.Dispatch(&suite.event)
+	err := suite.eventDispatcher.Dispatch(context.Background(), &suite.event)
+	suite.Nil(err)
 	eh.AssertExpectations(suite.T())
 	eh2.AssertExpectations(suite.T())
 	eh.AssertNumberOfCalls(suite.T(), "Handle", 1)
 	eh2.AssertNumberOfCalls(suite.T(), "Handle", 1)
 }
 
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_JoinsHandlerErrors() {
+	eh := &MockHandler{}
+	eh.On("Handle", &suite.event).Return(errors.New("publish failed"))
+
+	eh2 := &MockHandler{}
+	eh2.On("Handle", &suite.event).Return(nil)
+
+	eh3 := &MockHandler{}
+	eh3.On("Handle", &suite.event).Return(errors.New("timed out"))
+
+	suite.eventDispatcher.Register(suite.event.GetName(), eh)
+	suite.eventDispatcher.Register(suite.event.GetName(), eh2)
+	suite.eventDispatcher.Register(suite.event.GetName(), eh3)
+
+	err := suite.eventDispatcher.Dispatch(context.Background(), &suite.event)
+	suite.ErrorContains(err, "publish failed")
+	suite.ErrorContains(err, "timed out")
+	eh2.AssertNumberOfCalls(suite.T(), "Handle", 1)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_RecoversPanics() {
+	panicking := HandlerFunc(func(ctx context.Context, event EventInterface) error {
+		panic("boom")
+	})
+	eh := &MockHandler{}
+	eh.On("Handle", &suite.event).Return(nil)
+
+	suite.eventDispatcher.Register(suite.event.GetName(), &panicking)
+	suite.eventDispatcher.Register(suite.event.GetName(), eh)
+
+	err := suite.eventDispatcher.Dispatch(context.Background(), &suite.event)
+	suite.ErrorIs(err, ErrHandlerPanicked)
+	eh.AssertNumberOfCalls(suite.T(), "Handle", 1)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_WithMiddleware() {
+	calls := []string{}
+	trace := func(name string) Middleware {
+		return func(next EventHandlerInterface) EventHandlerInterface {
+			return HandlerFunc(func(ctx context.Context, event EventInterface) error {
+				calls = append(calls, name)
+				return next.Handle(ctx, event)
+			})
+		}
+	}
+	eh := &MockHandler{}
+	eh.On("Handle", &suite.event).Return(nil)
+	eh2 := &MockHandler{}
+	eh2.On("Handle", &suite.event2).Return(nil)
+
+	suite.eventDispatcher.Register(suite.event.GetName(), eh, trace("outer"), trace("inner"))
+	suite.eventDispatcher.Register(suite.event2.GetName(), eh2)
+
+	suite.Nil(suite.eventDispatcher.Dispatch(context.Background(), &suite.event))
+	suite.Nil(suite.eventDispatcher.Dispatch(context.Background(), &suite.event2))
+	suite.Equal([]string{"outer", "inner"}, calls)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_MatchesPatterns() {
+	all := &MockHandler{}
+	all.On("Handle", mock.Anything).Return(nil)
+	prefixed := &MockHandler{}
+	prefixed.On("Handle", mock.Anything).Return(nil)
+
+	suite.eventDispatcher.Register("*", all)
+	suite.eventDispatcher.Register("test2*", prefixed)
+
+	suite.Nil(suite.eventDispatcher.Dispatch(context.Background(), &suite.event))
+	suite.Nil(suite.eventDispatcher.Dispatch(context.Background(), &suite.event2))
+	all.AssertNumberOfCalls(suite.T(), "Handle", 2)
+	prefixed.AssertNumberOfCalls(suite.T(), "Handle", 1)
+	prefixed.AssertCalled(suite.T(), "Handle", &suite.event2)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_ByPriority() {
+	calls := make(chan string, 3)
+	record := func(name string) *HandlerFunc {
+		handler := HandlerFunc(func(ctx context.Context, event EventInterface) error {
+			calls <- name
+			return nil
+		})
+		return &handler
+	}
+
+	suite.eventDispatcher.Register(suite.event.GetName(), record("default"))
+	suite.eventDispatcher.RegisterWithPriority("*", 10, record("audit"))
+	suite.eventDispatcher.RegisterWithPriority(suite.event.GetName(), 5, record("validate"))
+
+	suite.Nil(suite.eventDispatcher.Dispatch(context.Background(), &suite.event))
+	close(calls)
+	order := []string{}
+	for name := range calls {
+		order = append(order, name)
+	}
+	suite.Equal([]string{"audit", "validate", "default"}, order)
+}
+
+// Run with -race to check registering and dispatching concurrently.
+func (suite *EventDispatcherTestSuite) TestEventDispatcher_ConcurrentUse() {
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			handler := &TestEventHandler{ID: i}
+			suite.Nil(suite.eventDispatcher.Register(suite.event.GetName(), handler))
+			suite.True(suite.eventDispatcher.Has(suite.event.GetName(), handler))
+			suite.Nil(suite.eventDispatcher.Remove(suite.event.GetName(), handler))
+		}()
+		go func() {
+			defer wg.Done()
+			suite.Nil(suite.eventDispatcher.Dispatch(context.Background(), &suite.event))
+		}()
+	}
+	wg.Wait()
+	suite.Empty(suite.eventDispatcher.handlers[suite.event.GetName()])
+}
+
+func TestNewFactory(t *testing.T) {
+	factory := NewFactory(NewTestEvent)
+
+	first := factory("first")
+	second := factory("second")
+
+	assert.Equal(t, "first", first.GetPayload())
+	assert.Equal(t, "second", second.GetPayload())
+	assert.NotSame(t, first, second)
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(EventDispatcherTestSuite))
 }
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	ID       string      `json:"-"`
+	Name     string      `json:"name"`
+	Payload  interface{} `json:"payload"`
+	DateTime time.Time   `json:"-"`
+}
+
+func (e *testEvent) GetID() string           { return e.ID }
+func (e *testEvent) GetName() string         { return e.Name }
+func (e *testEvent) GetPayload() interface{} { return e.Payload }
+func (e *testEvent) GetDateTime() time.Time {
+	if e.DateTime.IsZero() {
+		return time.Now()
+	}
+	return e.DateTime
+}
+
+func TestNewSchemaValidator(t *testing.T) {
+	validator, err := NewSchemaValidator()
+
+	require.Nil(t, err)
+	assert.Len(t, validator.schemas, len(schemaFiles))
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("accepts valid events", func(t *testing.T) {
+		for _, data := range []string{
+			`{"name":"TransactionCreated","payload":{"id":"tx1","account_id_from":"a1","account_id_to":"a2","amount":10,"created_at":"2025-03-03T10:00:00Z"}}`,
+			`{"name":"BalanceUpdated","payload":{"account_id":"a1","balance":90,"sequence":3,"transaction_id":"tx1","created_at":"2025-03-03T10:00:00Z"}}`,
+			`{"name":"BalanceUpdated","payload":{"account_id_from":"a1","account_id_to":"a2","balance_account_id_from":90,"balance_account_id_to":110}}`,
+		} {
+			assert.Nil(t, Validate([]byte(data)), data)
+		}
+	})
+
+	t.Run("rejects events that do not match their schema", func(t *testing.T) {
+		for _, data := range []string{
+			`{"name":"TransactionCreated","payload":{"id":"tx1","account_id_from":"a1","account_id_to":"a2","amount":-10,"created_at":"2025-03-03T10:00:00Z"}}`,
+			`{"name":"TransactionCreated","payload":{"id":"tx1","account_id_from":"a1","account_id_to":"a2","amount":10,"created_at":"yesterday"}}`,
+			`{"name":"BalanceUpdated","payload":{"balance":90}}`,
+			`{"name":"BalanceUpdated","payload":{"account_id":42,"balance":90}}`,
+			`{"name":"BalanceUpdated","payload":{"account_id":"a1","balance":"90"}}`,
+		} {
+			err := Validate([]byte(data))
+			assert.ErrorContains(t, err, "does not match its schema", data)
+		}
+	})
+
+	t.Run("rejects events without a schema", func(t *testing.T) {
+		assert.ErrorIs(t, Validate([]byte(`{"name":"AccountClosed","payload":{}}`)), ErrNoSchema)
+		assert.ErrorIs(t, Validate([]byte(`[]`)), ErrNoSchema)
+	})
+
+	t.Run("rejects malformed JSON", func(t *testing.T) {
+		assert.NotNil(t, Validate([]byte(`not json`)))
+	})
+}
+
+func TestValidateEvent(t *testing.T) {
+	event := &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": "a1", "balance": 90.0}}
+
+	assert.Nil(t, ValidateEvent(event))
+
+	event = &testEvent{Name: "BalanceUpdated", Payload: map[string]interface{}{"account_id": ""}}
+	assert.NotNil(t, ValidateEvent(event))
+}
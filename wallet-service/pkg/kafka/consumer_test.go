@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConsumeTestConsumer(t *testing.T, values ...string) (*Consumer, string) {
+	cluster, err := ckafka.NewMockCluster(1)
+	require.Nil(t, err)
+	t.Cleanup(cluster.Close)
+
+	produceTestMessages(t, cluster.BootstrapServers(), "balances", values...)
+	return NewConsumer(&ckafka.ConfigMap{
+		"bootstrap.servers": cluster.BootstrapServers(),
+		"group.id":          "balance-service-test",
+		"auto.offset.reset": "earliest",
+	}, []string{"balances"}), cluster.BootstrapServers()
+}
+
+func committedOffset(t *testing.T, bootstrapServers string) ckafka.Offset {
+	consumer, err := ckafka.NewConsumer(&ckafka.ConfigMap{
+		"bootstrap.servers": bootstrapServers,
+		"group.id":          "balance-service-test",
+	})
+	require.Nil(t, err)
+	defer consumer.Close()
+
+	topic := "balances"
+	committed, err := consumer.Committed([]ckafka.TopicPartition{{Topic: &topic, Partition: 0}}, 10000)
+	require.Nil(t, err)
+	return committed[0].Offset
+}
+
+func TestConsumerConsume(t *testing.T) {
+	t.Run("commits handled messages and stops when the context is cancelled", func(t *testing.T) {
+		consumer, bootstrapServers := newConsumeTestConsumer(t, "a", "b", "c")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var assigned []ckafka.TopicPartition
+		consumer.OnAssigned = func(partitions []ckafka.TopicPartition) {
+			assigned = append(assigned, partitions...)
+		}
+		var got []string
+		err := consumer.Consume(ctx, func(msg *ckafka.Message) error {
+			got = append(got, string(msg.Value))
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, got)
+		assert.NotEmpty(t, assigned)
+		assert.Equal(t, ckafka.Offset(3), committedOffset(t, bootstrapServers))
+	})
+
+	t.Run("redelivers a message whose handler failed", func(t *testing.T) {
+		consumer, bootstrapServers := newConsumeTestConsumer(t, "a", "b", "c")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var got []string
+		failed := false
+		err := consumer.Consume(ctx, func(msg *ckafka.Message) error {
+			got = append(got, string(msg.Value))
+			if string(msg.Value) == "b" && !failed {
+				failed = true
+				return errors.New("database unavailable")
+			}
+			if string(msg.Value) == "c" {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b", "b", "c"}, got)
+		assert.Equal(t, ckafka.Offset(3), committedOffset(t, bootstrapServers))
+	})
+
+	t.Run("returns setup errors instead of panicking", func(t *testing.T) {
+		consumer := NewConsumer(&ckafka.ConfigMap{"bootstrap.servers": "localhost:0"}, []string{"balances"})
+
+		err := consumer.Consume(context.Background(), func(msg *ckafka.Message) error {
+			return nil
+		})
+
+		assert.NotNil(t, err)
+	})
+}
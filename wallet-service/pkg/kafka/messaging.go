@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"wallet/pkg/messaging"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Publisher publishes broker-agnostic messages through a Producer.
+type Publisher struct {
+	Producer *Producer
+}
+
+func NewPublisher(producer *Producer) *Publisher {
+	return &Publisher{Producer: producer}
+}
+
+// Publish produces msg and waits for its delivery report or for ctx to be
+// done.
+func (p *Publisher) Publish(ctx context.Context, msg *messaging.Message) error {
+	return p.Producer.PublishMessageContext(ctx, FromMessage(msg))
+}
+
+// Subscribe consumes the consumer's topics, handing every message to handle
+// as a broker-agnostic message. See Consume for commit and redelivery.
+func (c *Consumer) Subscribe(ctx context.Context, handle messaging.Handler) error {
+	return c.Consume(ctx, func(msg *ckafka.Message) error {
+		return handle(ctx, ToMessage(msg))
+	})
+}
+
+// ToMessage converts a consumed Kafka message. When a header repeats, the
+// last value wins.
+func ToMessage(msg *ckafka.Message) *messaging.Message {
+	converted := &messaging.Message{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Timestamp: msg.Timestamp,
+	}
+	if msg.TopicPartition.Topic != nil {
+		converted.Topic = *msg.TopicPartition.Topic
+	}
+	if len(msg.Headers) > 0 {
+		converted.Headers = make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			converted.Headers[header.Key] = string(header.Value)
+		}
+	}
+	return converted
+}
+
+// FromMessage builds the Kafka message to produce for msg, leaving the
+// partition to the partitioner.
+func FromMessage(msg *messaging.Message) *ckafka.Message {
+	topic := msg.Topic
+	converted := &ckafka.Message{
+		TopicPartition: ckafka.TopicPartition{Topic: &topic, Partition: ckafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+	}
+	keys := make([]string, 0, len(msg.Headers))
+	for key := range msg.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		converted.Headers = append(converted.Headers, ckafka.Header{Key: key, Value: []byte(msg.Headers[key])})
+	}
+	return converted
+}
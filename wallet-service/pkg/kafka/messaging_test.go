@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wallet/pkg/messaging"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMessage(t *testing.T) {
+	topic := "balances"
+	createdAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+
+	msg := ToMessage(&ckafka.Message{
+		TopicPartition: ckafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 41},
+		Key:            []byte("account1"),
+		Value:          []byte("a"),
+		Headers:        []ckafka.Header{{Key: "trace", Value: []byte("abc")}, {Key: "trace", Value: []byte("def")}},
+		Timestamp:      createdAt,
+	})
+
+	assert.Equal(t, &messaging.Message{
+		Topic:     "balances",
+		Partition: 2,
+		Offset:    41,
+		Key:       []byte("account1"),
+		Value:     []byte("a"),
+		Headers:   map[string]string{"trace": "def"},
+		Timestamp: createdAt,
+	}, msg)
+}
+
+func TestFromMessage(t *testing.T) {
+	msg := FromMessage(&messaging.Message{
+		Topic:     "balances",
+		Partition: 2,
+		Key:       []byte("account1"),
+		Value:     []byte("a"),
+		Headers:   map[string]string{"b": "2", "a": "1"},
+	})
+
+	assert.Equal(t, "balances", *msg.TopicPartition.Topic)
+	assert.Equal(t, ckafka.PartitionAny, msg.TopicPartition.Partition)
+	assert.Equal(t, []byte("account1"), msg.Key)
+	assert.Equal(t, []ckafka.Header{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}}, msg.Headers)
+}
+
+func TestPublisherAndSubscriber(t *testing.T) {
+	cluster, err := ckafka.NewMockCluster(1)
+	require.Nil(t, err)
+	defer cluster.Close()
+
+	producer, err := NewKafkaProducer(&ckafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers()})
+	require.Nil(t, err)
+	defer producer.Close()
+	var publisher messaging.Publisher = NewPublisher(producer)
+	err = publisher.Publish(context.Background(), &messaging.Message{
+		Topic:   "balances",
+		Key:     []byte("account1"),
+		Value:   []byte("a"),
+		Headers: map[string]string{"trace": "abc"},
+	})
+	require.Nil(t, err)
+
+	var subscriber messaging.Subscriber = NewConsumer(&ckafka.ConfigMap{
+		"bootstrap.servers": cluster.BootstrapServers(),
+		"group.id":          "balance-service-test",
+		"auto.offset.reset": "earliest",
+	}, []string{"balances"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got *messaging.Message
+	err = subscriber.Subscribe(ctx, func(ctx context.Context, msg *messaging.Message) error {
+		got = msg
+		cancel()
+		return nil
+	})
+
+	require.Nil(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "balances/0/0", messaging.MessageID(got))
+	assert.Equal(t, []byte("a"), got.Value)
+	assert.Equal(t, "abc", got.Headers["trace"])
+}
+
+func TestPublisherPublishStopsWithContext(t *testing.T) {
+	// Nothing listens there, so no delivery report arrives before the
+	// message times out
+	producer, err := NewKafkaProducer(&ckafka.ConfigMap{
+		"bootstrap.servers":  "127.0.0.1:1",
+		"message.timeout.ms": 1000,
+	})
+	require.Nil(t, err)
+	defer producer.Close()
+	publisher := NewPublisher(producer)
+	msg := &messaging.Message{Topic: "balances", Key: []byte("account1"), Value: []byte("a")}
+
+	t.Run("returns once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := publisher.Publish(ctx, msg)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("does not produce with a cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := publisher.Publish(ctx, msg)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
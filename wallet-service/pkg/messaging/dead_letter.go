@@ -0,0 +1,143 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DeadLetterSuffix is appended to a topic name to get its dead-letter
+	// topic.
+	DeadLetterSuffix = ".dlq"
+	// QuarantineSuffix is appended to a topic name to get the topic holding
+	// its invalid messages.
+	QuarantineSuffix = ".quarantine"
+)
+
+// Headers set on dead-lettered messages.
+const (
+	HeaderOriginalTopic     = "dlq.original.topic"
+	HeaderOriginalPartition = "dlq.original.partition"
+	HeaderOriginalOffset    = "dlq.original.offset"
+	HeaderError             = "dlq.error"
+	HeaderAttempts          = "dlq.attempts"
+	HeaderFailedAt          = "dlq.failed_at"
+)
+
+var ErrNotDeadLettered = errors.New("message has no original topic header")
+
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterSuffix
+}
+
+func QuarantineTopic(topic string) string {
+	return topic + QuarantineSuffix
+}
+
+// DeadLetterQueue parks messages that could not be handled, quarantines
+// invalid ones and re-drives them onto their source topic.
+type DeadLetterQueue struct {
+	Publisher Publisher
+}
+
+func NewDeadLetterQueue(publisher Publisher) *DeadLetterQueue {
+	return &DeadLetterQueue{Publisher: publisher}
+}
+
+// Park publishes a copy of the message to the dead-letter topic of its source
+// topic, recording where it came from and why it failed in headers.
+func (q *DeadLetterQueue) Park(ctx context.Context, msg *Message, cause error, attempts int) error {
+	return q.Publisher.Publish(ctx, NewDeadLetterMessage(msg, cause, attempts, time.Now()))
+}
+
+// Quarantine publishes a copy of an invalid message to the quarantine topic
+// of its source topic, with the same headers as a parked message.
+func (q *DeadLetterQueue) Quarantine(ctx context.Context, msg *Message, cause error) error {
+	quarantined := NewDeadLetterMessage(msg, cause, 1, time.Now())
+	quarantined.Topic = QuarantineTopic(msg.Topic)
+	return q.Publisher.Publish(ctx, quarantined)
+}
+
+// Redrive publishes a dead-lettered message back onto its source topic.
+func (q *DeadLetterQueue) Redrive(ctx context.Context, msg *Message) error {
+	redriven, err := NewRedriveMessage(msg)
+	if err != nil {
+		return err
+	}
+	return q.Publisher.Publish(ctx, redriven)
+}
+
+// WithRetry wraps handle so failures are retried according to the policy and
+// messages that still fail are parked on the dead-letter queue. Invalid
+// messages are quarantined without being retried. The returned handler only
+// fails when parking fails or when ctx is done, leaving the message to be
+// delivered again rather than parked.
+func (q *DeadLetterQueue) WithRetry(policy RetryPolicy, handle Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		attempts, err := policy.Run(ctx, func() error {
+			return handle(ctx, msg)
+		})
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if IsInvalid(err) {
+			log.Printf("Quarantining invalid message %s: %v", MessageID(msg), err)
+			err = q.Quarantine(ctx, msg, err)
+		} else {
+			log.Printf("Dead-lettering message %s after %d attempt(s): %v", MessageID(msg), attempts, err)
+			err = q.Park(ctx, msg, err, attempts)
+		}
+		if err != nil {
+			return fmt.Errorf("dead-lettering message %s: %w", MessageID(msg), err)
+		}
+		return nil
+	}
+}
+
+func NewDeadLetterMessage(msg *Message, cause error, attempts int, failedAt time.Time) *Message {
+	headers := withoutDeadLetterHeaders(msg.Headers)
+	headers[HeaderOriginalTopic] = msg.Topic
+	headers[HeaderOriginalPartition] = strconv.Itoa(int(msg.Partition))
+	headers[HeaderOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+	headers[HeaderError] = cause.Error()
+	headers[HeaderAttempts] = strconv.Itoa(attempts)
+	headers[HeaderFailedAt] = failedAt.UTC().Format(time.RFC3339)
+	return &Message{
+		Topic:   DeadLetterTopic(msg.Topic),
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// NewRedriveMessage rebuilds the original message from a dead-lettered one.
+func NewRedriveMessage(msg *Message) (*Message, error) {
+	topic := msg.Headers[HeaderOriginalTopic]
+	if topic == "" {
+		return nil, ErrNotDeadLettered
+	}
+	return &Message{
+		Topic:   topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: withoutDeadLetterHeaders(msg.Headers),
+	}, nil
+}
+
+func withoutDeadLetterHeaders(headers map[string]string) map[string]string {
+	kept := map[string]string{}
+	for key, value := range headers {
+		if !strings.HasPrefix(key, "dlq.") {
+			kept[key] = value
+		}
+	}
+	return kept
+}
@@ -0,0 +1,300 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// MemoryBroker keeps topics in memory so services and tests can publish and
+// consume without Kafka. Topics are created on first use with a fixed number
+// of partitions; keyed messages always land on the same partition. Consumer
+// groups track a committed offset per partition and share partitions among
+// their members.
+type MemoryBroker struct {
+	Partitions int
+	// RedeliveryDelay paces redelivery of a message whose handler failed.
+	RedeliveryDelay time.Duration
+
+	mu      sync.Mutex
+	topics  map[string][][]*Message
+	groups  map[string]*memoryGroup
+	next    uint32
+	members int
+	changed chan struct{}
+}
+
+type memoryGroup struct {
+	members []*memoryMember
+	offsets map[string]int64
+}
+
+// memoryMember identifies a subscriber within its group.
+type memoryMember struct {
+	id int
+	// next is where the member's next poll starts among its partitions
+	next int
+}
+
+func NewMemoryBroker(partitions int) *MemoryBroker {
+	if partitions < 1 {
+		partitions = 1
+	}
+	return &MemoryBroker{
+		Partitions:      partitions,
+		RedeliveryDelay: time.Second,
+		topics:          map[string][][]*Message{},
+		groups:          map[string]*memoryGroup{},
+		changed:         make(chan struct{}),
+	}
+}
+
+// Publish appends msg to its topic. Messages without a key are spread over
+// the partitions in turn.
+func (b *MemoryBroker) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.topic(msg.Topic)
+	stored := msg.clone()
+	stored.Partition = b.partition(msg.Key, len(partitions))
+	stored.Offset = int64(len(partitions[stored.Partition]))
+	if stored.Timestamp.IsZero() {
+		stored.Timestamp = time.Now()
+	}
+	partitions[stored.Partition] = append(partitions[stored.Partition], stored)
+	b.notify()
+	return nil
+}
+
+// Messages returns a copy of everything published to topic, partition by
+// partition.
+func (b *MemoryBroker) Messages(topic string) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages := []*Message{}
+	for _, partition := range b.topics[topic] {
+		for _, msg := range partition {
+			messages = append(messages, msg.clone())
+		}
+	}
+	return messages
+}
+
+// Committed returns the next offset group will read from a partition.
+func (b *MemoryBroker) Committed(group, topic string, partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if g, ok := b.groups[group]; ok {
+		return g.offsets[memoryPartitionKey(topic, partition)]
+	}
+	return 0
+}
+
+// Replay reads topics partition by partition. See Replayer.
+func (b *MemoryBroker) Replay(ctx context.Context, topics []string, from ReplayPosition, handle Handler) (Offsets, error) {
+	ends := Offsets{}
+	for _, topic := range topics {
+		// Published messages never change, so the slices can be read unlocked
+		b.mu.Lock()
+		partitions := append([][]*Message{}, b.topic(topic)...)
+		b.mu.Unlock()
+
+		for partition, messages := range partitions {
+			key := TopicPartition{Topic: topic, Partition: int32(partition)}
+			ends[key] = int64(len(messages))
+			start, ok := from.Offsets[key]
+			switch {
+			case ok:
+			case !from.Timestamp.IsZero():
+				start = int64(len(messages))
+				for _, msg := range messages {
+					if !msg.Timestamp.Before(from.Timestamp) {
+						start = msg.Offset
+						break
+					}
+				}
+			default:
+				start = from.Offset
+			}
+
+			for offset := start; offset < int64(len(messages)); offset++ {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				msg := messages[offset].clone()
+				if err := handle(ctx, msg); err != nil {
+					return nil, fmt.Errorf("replaying %s: %w", MessageID(msg), err)
+				}
+			}
+		}
+	}
+	return ends, nil
+}
+
+// Subscriber returns a subscriber consuming topics as a member of group. New
+// groups start at the beginning of every partition.
+func (b *MemoryBroker) Subscriber(group string, topics ...string) *MemorySubscriber {
+	return &MemorySubscriber{
+		Broker: b,
+		Group:  group,
+		Topics: topics,
+	}
+}
+
+type MemorySubscriber struct {
+	Broker *MemoryBroker
+	Group  string
+	Topics []string
+}
+
+// Subscribe joins the group and handles messages of the partitions assigned
+// to this member until ctx is cancelled. A failed message is delivered again
+// after the broker's redelivery delay. While members join or leave, a message
+// can briefly be handled by two members, as with any at-least-once broker.
+func (s *MemorySubscriber) Subscribe(ctx context.Context, handle Handler) error {
+	member := s.Broker.join(s.Group)
+	defer s.Broker.leave(s.Group, member)
+
+	for {
+		msg, changed := s.Broker.poll(s.Group, member, s.Topics)
+		if msg == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-changed:
+			}
+			continue
+		}
+		if err := handle(ctx, msg); err != nil {
+			log.Printf("Failed to handle message %s, redelivering: %v", MessageID(msg), err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(s.Broker.RedeliveryDelay):
+			}
+			continue
+		}
+		s.Broker.commit(s.Group, msg)
+	}
+}
+
+func (b *MemoryBroker) join(group string) *memoryMember {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[group]
+	if !ok {
+		g = &memoryGroup{offsets: map[string]int64{}}
+		b.groups[group] = g
+	}
+	b.members++
+	member := &memoryMember{id: b.members}
+	g.members = append(g.members, member)
+	b.notify()
+	return member
+}
+
+func (b *MemoryBroker) leave(group string, member *memoryMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.groups[group]
+	for i, m := range g.members {
+		if m == member {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+	b.notify()
+}
+
+// poll returns the next uncommitted message on the partitions assigned to
+// member, or nil and a channel that is closed on the next change. Partitions
+// are dealt to the group's members in turn, and each poll starts at the
+// partition after the one the previous message came from, so a busy or
+// failing partition does not hold the others back.
+func (b *MemoryBroker) poll(group string, member *memoryMember, topics []string) (*Message, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.groups[group]
+	index := 0
+	for i, m := range g.members {
+		if m == member {
+			index = i
+		}
+	}
+	assigned := []memoryPartition{}
+	for _, topic := range topics {
+		for partition := range b.topic(topic) {
+			if partition%len(g.members) == index {
+				assigned = append(assigned, memoryPartition{topic: topic, partition: int32(partition)})
+			}
+		}
+	}
+	for i := range assigned {
+		next := (member.next + i) % len(assigned)
+		p := assigned[next]
+		messages := b.topics[p.topic][p.partition]
+		offset := g.offsets[memoryPartitionKey(p.topic, p.partition)]
+		if offset < int64(len(messages)) {
+			member.next = next + 1
+			return messages[offset].clone(), nil
+		}
+	}
+	return nil, b.changed
+}
+
+type memoryPartition struct {
+	topic     string
+	partition int32
+}
+
+func (b *MemoryBroker) commit(group string, msg *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offsets := b.groups[group].offsets
+	key := memoryPartitionKey(msg.Topic, msg.Partition)
+	if msg.Offset+1 > offsets[key] {
+		offsets[key] = msg.Offset + 1
+	}
+}
+
+// topic returns the partitions of a topic, creating it on first use. The
+// caller holds the lock.
+func (b *MemoryBroker) topic(name string) [][]*Message {
+	partitions, ok := b.topics[name]
+	if !ok {
+		partitions = make([][]*Message, b.Partitions)
+		b.topics[name] = partitions
+	}
+	return partitions
+}
+
+func (b *MemoryBroker) partition(key []byte, partitions int) int32 {
+	if len(key) == 0 {
+		b.next++
+		return int32(b.next % uint32(partitions))
+	}
+	return keyPartition(key, partitions)
+}
+
+// notify wakes every subscriber waiting for a change. The caller holds the
+// lock.
+func (b *MemoryBroker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func memoryPartitionKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s[%d]", topic, partition)
+}
@@ -0,0 +1,62 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Message is a record on a topic, independent of the broker carrying it.
+// Publishers pick the partition from the key; Partition, Offset and Timestamp
+// are set on consumed messages.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+// Handler processes one consumed message. Returning an error leaves the
+// message uncommitted so it is delivered again.
+type Handler func(ctx context.Context, msg *Message) error
+
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// Subscriber delivers the messages of its topics to handle, one at a time and
+// in order per partition, committing each only after handle succeeded. It
+// returns nil once ctx is cancelled.
+type Subscriber interface {
+	Subscribe(ctx context.Context, handle Handler) error
+}
+
+// MessageID identifies a message by its position, which stays the same when
+// the message is delivered again.
+func MessageID(msg *Message) string {
+	return fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+}
+
+// keyPartition maps a key to a partition, so messages with the same key stay
+// in order on one partition.
+func keyPartition(key []byte, partitions int) int32 {
+	hash := fnv.New32a()
+	hash.Write(key)
+	return int32(hash.Sum32() % uint32(partitions))
+}
+
+// clone copies msg so a handler cannot change the stored message.
+func (msg *Message) clone() *Message {
+	copied := *msg
+	if msg.Headers != nil {
+		copied.Headers = make(map[string]string, len(msg.Headers))
+		for key, value := range msg.Headers {
+			copied.Headers[key] = value
+		}
+	}
+	return &copied
+}
@@ -0,0 +1,116 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryPolicy retries a failing operation with exponential backoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Sleep waits between attempts, returning early with an error when ctx is
+	// done; nil uses a timer.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// Backoff returns the wait after the given failed attempt, counted from 1.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
+// Run calls fn until it succeeds, returns a permanent error or the attempts
+// are exhausted. It returns the number of attempts made and the last error,
+// or ctx's error when ctx is done while waiting for the next attempt.
+func (p RetryPolicy) Run(ctx context.Context, fn func() error) (int, error) {
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	attempt := 1
+	for {
+		err := fn()
+		if err == nil {
+			return attempt, nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= p.MaxAttempts {
+			return attempt, err
+		}
+		if err := sleep(ctx, p.Backoff(attempt)); err != nil {
+			return attempt, err
+		}
+		attempt++
+	}
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying cannot fix, such as a message that
+// does not decode.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type invalidError struct {
+	err error
+}
+
+func (e *invalidError) Error() string { return e.err.Error() }
+
+func (e *invalidError) Unwrap() error { return e.err }
+
+// Invalid marks a message that is malformed or does not match its schema.
+// Invalid errors are permanent, and WithRetry quarantines such messages
+// instead of dead-lettering them.
+func Invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return Permanent(&invalidError{err: err})
+}
+
+func IsInvalid(err error) bool {
+	var invalid *invalidError
+	return errors.As(err, &invalid)
+}
@@ -0,0 +1,101 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRetryPolicy(slept *[]time.Duration) RetryPolicy {
+	policy := NewRetryPolicy()
+	policy.Sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return nil
+	}
+	return policy
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 800*time.Millisecond, policy.Backoff(4))
+	assert.Equal(t, time.Second, policy.Backoff(5))
+	assert.Equal(t, time.Second, policy.Backoff(50))
+}
+
+func TestRetryPolicyRun(t *testing.T) {
+	t.Run("returns after the first success", func(t *testing.T) {
+		var slept []time.Duration
+		calls := 0
+
+		attempts, err := newTestRetryPolicy(&slept).Run(context.Background(), func() error {
+			calls++
+			if calls < 3 {
+				return errors.New("transient")
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, slept)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		var slept []time.Duration
+		failure := errors.New("still failing")
+
+		attempts, err := newTestRetryPolicy(&slept).Run(context.Background(), func() error {
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 5, attempts)
+		assert.Len(t, slept, 4)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		var slept []time.Duration
+		failure := errors.New("malformed")
+
+		attempts, err := newTestRetryPolicy(&slept).Run(context.Background(), func() error {
+			return Permanent(failure)
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, attempts)
+		assert.Empty(t, slept)
+	})
+
+	t.Run("does not retry invalid messages", func(t *testing.T) {
+		var slept []time.Duration
+		failure := errors.New("does not match its schema")
+
+		attempts, err := newTestRetryPolicy(&slept).Run(context.Background(), func() error {
+			return Invalid(failure)
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.True(t, IsInvalid(err))
+		assert.Equal(t, 1, attempts)
+		assert.Empty(t, slept)
+	})
+	t.Run("stops waiting when ctx is done", func(t *testing.T) {
+		policy := NewRetryPolicy()
+		policy.InitialBackoff = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+
+		attempts, err := policy.Run(ctx, func() error {
+			cancel()
+			return errors.New("transient")
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,433 @@
+package messaging
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQLiteBroker is a durable event log kept in a SQLite database, for running
+// the services without Kafka. Every topic is an append-only table of
+// partitioned messages and consumer groups keep their committed offsets in
+// consumer_offsets, so messages survive restarts and new groups replay a
+// topic from the beginning. Several processes can share one database file
+// opened with SQLiteDSN.
+//
+// The members of a group lease the partitions they read in consumer_leases,
+// so a partition is read by one member at a time. Partitions are not
+// balanced: the first member claims every free one, and the others take
+// over the partitions of a member that stops or stops polling for LeaseTTL.
+type SQLiteBroker struct {
+	DB         *sql.DB
+	Partitions int
+	// PollInterval is how long a subscriber waits once it is caught up.
+	PollInterval time.Duration
+	// RedeliveryDelay paces redelivery of a message whose handler failed.
+	RedeliveryDelay time.Duration
+	// LeaseTTL is how long a partition stays claimed by a member that no
+	// longer polls it.
+	LeaseTTL time.Duration
+
+	mu     sync.Mutex
+	topics map[string]bool
+	next   uint32
+}
+
+// SQLiteDSN returns the data source name for an event log file. The busy
+// timeout makes processes sharing the file wait for each other's writes, and
+// WAL lets them read while another one writes.
+func SQLiteDSN(path string) string {
+	return "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+// NewSQLiteBroker creates the offsets and leases tables when missing. Every process
+// sharing the database must use the same number of partitions.
+func NewSQLiteBroker(db *sql.DB, partitions int) (*SQLiteBroker, error) {
+	if partitions < 1 {
+		partitions = 1
+	}
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS consumer_offsets (
+		group_id TEXT NOT NULL,
+		topic TEXT NOT NULL,
+		partition_id INTEGER NOT NULL,
+		next_offset INTEGER NOT NULL,
+		PRIMARY KEY (group_id, topic, partition_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS consumer_leases (
+		group_id TEXT NOT NULL,
+		topic TEXT NOT NULL,
+		partition_id INTEGER NOT NULL,
+		member TEXT NOT NULL,
+		expires_at INTEGER NOT NULL,
+		PRIMARY KEY (group_id, topic, partition_id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteBroker{
+		DB:              db,
+		Partitions:      partitions,
+		PollInterval:    200 * time.Millisecond,
+		RedeliveryDelay: time.Second,
+		LeaseTTL:        30 * time.Second,
+		topics:          map[string]bool{},
+	}, nil
+}
+
+// Publish appends msg to its topic. The offset is assigned by the insert
+// itself, so concurrent publishers never reuse one.
+func (b *SQLiteBroker) Publish(ctx context.Context, msg *Message) error {
+	table, err := b.topic(ctx, msg.Topic)
+	if err != nil {
+		return err
+	}
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return err
+	}
+	createdAt := msg.Timestamp
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	partition := b.partition(msg.Key)
+
+	_, err = b.DB.ExecContext(ctx, "INSERT INTO "+table+` (partition_id, log_offset, message_key, value, headers, created_at)
+		SELECT ?, COALESCE(MAX(log_offset) + 1, 0), ?, ?, ?, ? FROM `+table+" WHERE partition_id = ?",
+		partition, msg.Key, msg.Value, string(headers), createdAt.UnixNano(), partition,
+	)
+	return err
+}
+
+// Messages returns everything published to topic, partition by partition.
+func (b *SQLiteBroker) Messages(ctx context.Context, topic string) ([]*Message, error) {
+	table, err := b.topic(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.DB.QueryContext(ctx, `SELECT partition_id, log_offset, message_key, value, headers, created_at
+		FROM `+table+" ORDER BY partition_id, log_offset")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*Message{}
+	for rows.Next() {
+		msg, err := scanSQLiteMessage(rows, topic)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Committed returns the next offset group will read from a partition.
+func (b *SQLiteBroker) Committed(ctx context.Context, group, topic string, partition int32) (int64, error) {
+	var offset int64
+	err := b.DB.QueryRowContext(ctx,
+		"SELECT next_offset FROM consumer_offsets WHERE group_id = ? AND topic = ? AND partition_id = ?",
+		group, topic, partition,
+	).Scan(&offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return offset, err
+}
+
+// Seek moves the committed offset of group on a partition, so the group
+// replays or skips messages from there on.
+func (b *SQLiteBroker) Seek(ctx context.Context, group, topic string, partition int32, offset int64) error {
+	_, err := b.DB.ExecContext(ctx, `INSERT INTO consumer_offsets (group_id, topic, partition_id, next_offset) VALUES (?, ?, ?, ?)
+		ON CONFLICT (group_id, topic, partition_id) DO UPDATE SET next_offset = excluded.next_offset`,
+		group, topic, partition, offset,
+	)
+	return err
+}
+
+// Replay reads topics partition by partition. See Replayer.
+func (b *SQLiteBroker) Replay(ctx context.Context, topics []string, from ReplayPosition, handle Handler) (Offsets, error) {
+	ends := Offsets{}
+	for _, topic := range topics {
+		table, err := b.topic(ctx, topic)
+		if err != nil {
+			return nil, err
+		}
+		for partition := int32(0); partition < int32(b.Partitions); partition++ {
+			end, err := b.replayPartition(ctx, table, topic, partition, from, handle)
+			if err != nil {
+				return nil, err
+			}
+			ends[TopicPartition{Topic: topic, Partition: partition}] = end
+		}
+	}
+	return ends, nil
+}
+
+// replayPartition replays one partition up to its current end, which it
+// returns.
+func (b *SQLiteBroker) replayPartition(ctx context.Context, table, topic string, partition int32, from ReplayPosition, handle Handler) (int64, error) {
+	var end int64
+	err := b.DB.QueryRowContext(ctx, "SELECT COALESCE(MAX(log_offset) + 1, 0) FROM "+table+" WHERE partition_id = ?", partition).Scan(&end)
+	if err != nil {
+		return 0, err
+	}
+	start, ok := from.Offsets[TopicPartition{Topic: topic, Partition: partition}]
+	switch {
+	case ok:
+	case !from.Timestamp.IsZero():
+		err := b.DB.QueryRowContext(ctx, "SELECT COALESCE(MIN(log_offset), ?) FROM "+table+" WHERE partition_id = ? AND created_at >= ?",
+			end, partition, from.Timestamp.UnixNano(),
+		).Scan(&start)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		start = from.Offset
+	}
+
+	// Read in pages so handle never runs with a query open
+	const page = 100
+	for start < end {
+		rows, err := b.DB.QueryContext(ctx, `SELECT partition_id, log_offset, message_key, value, headers, created_at
+			FROM `+table+" WHERE partition_id = ? AND log_offset >= ? AND log_offset < ? ORDER BY log_offset LIMIT ?",
+			partition, start, end, page,
+		)
+		if err != nil {
+			return 0, err
+		}
+		messages := []*Message{}
+		for rows.Next() {
+			msg, err := scanSQLiteMessage(rows, topic)
+			if err != nil {
+				rows.Close()
+				return 0, err
+			}
+			messages = append(messages, msg)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		for _, msg := range messages {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			if err := handle(ctx, msg); err != nil {
+				return 0, fmt.Errorf("replaying %s: %w", MessageID(msg), err)
+			}
+			start = msg.Offset + 1
+		}
+	}
+	return end, nil
+}
+
+// Subscriber returns a polling subscriber consuming topics for group. New
+// groups start at the beginning of every partition.
+func (b *SQLiteBroker) Subscriber(group string, topics ...string) *SQLiteSubscriber {
+	return &SQLiteSubscriber{
+		Broker: b,
+		Group:  group,
+		Topics: topics,
+	}
+}
+
+type SQLiteSubscriber struct {
+	Broker *SQLiteBroker
+	Group  string
+	Topics []string
+}
+
+// Subscribe polls the topics and handles the messages of the partitions it
+// leases until ctx is cancelled, committing each one after handle succeeded.
+// A failed message is delivered again after the broker's redelivery delay.
+// Database errors stop the subscriber, which gives up its leases when it
+// returns.
+func (s *SQLiteSubscriber) Subscribe(ctx context.Context, handle Handler) error {
+	member := uuid.NewString()
+	defer s.release(context.WithoutCancel(ctx), member)
+
+	next := 0
+	for ctx.Err() == nil {
+		msg, err := s.poll(ctx, member, &next)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if msg == nil {
+			sleep(ctx, s.Broker.PollInterval)
+			continue
+		}
+		if err := handle(ctx, msg); err != nil {
+			log.Printf("Failed to handle message %s, redelivering: %v", MessageID(msg), err)
+			sleep(ctx, s.Broker.RedeliveryDelay)
+			continue
+		}
+		// Commit even when handle cancelled ctx, the message was handled
+		if err := s.commit(context.WithoutCancel(ctx), member, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// poll renews the member's leases, claims the free partitions and returns
+// the oldest uncommitted message of one of its partitions, or nil when it is
+// caught up. Like the memory broker, it tries the partitions in turn from the
+// one after the partition of the previous message, kept in next, so a busy or
+// failing partition does not starve the others.
+func (s *SQLiteSubscriber) poll(ctx context.Context, member string, next *int) (*Message, error) {
+	tables := make([]string, len(s.Topics))
+	for i, topic := range s.Topics {
+		table, err := s.Broker.topic(ctx, topic)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.claim(ctx, member, topic); err != nil {
+			return nil, err
+		}
+		tables[i] = table
+	}
+	partitions := len(s.Topics) * s.Broker.Partitions
+	for i := 0; i < partitions; i++ {
+		current := (*next + i) % partitions
+		topic, table := s.Topics[current/s.Broker.Partitions], tables[current/s.Broker.Partitions]
+		partition := current % s.Broker.Partitions
+		// One partition at a time, so the lookup is a range of the log's
+		// primary key
+		row := s.Broker.DB.QueryRowContext(ctx, `SELECT l.partition_id, l.log_offset, l.message_key, l.value, l.headers, l.created_at
+			FROM `+table+` l
+			JOIN consumer_leases c ON c.group_id = ? AND c.topic = ? AND c.partition_id = l.partition_id AND c.member = ?
+			WHERE l.partition_id = ? AND l.log_offset >= COALESCE(
+				(SELECT next_offset FROM consumer_offsets WHERE group_id = ? AND topic = ? AND partition_id = ?), 0)
+			ORDER BY l.log_offset
+			LIMIT 1`,
+			s.Group, topic, member, partition, s.Group, topic, partition,
+		)
+		msg, err := scanSQLiteMessage(row, topic)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err == nil {
+			*next = current + 1
+		}
+		return msg, err
+	}
+	return nil, nil
+}
+
+func (s *SQLiteSubscriber) claim(ctx context.Context, member, topic string) error {
+	now := time.Now()
+	for partition := 0; partition < s.Broker.Partitions; partition++ {
+		_, err := s.Broker.DB.ExecContext(ctx, `INSERT INTO consumer_leases (group_id, topic, partition_id, member, expires_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (group_id, topic, partition_id) DO UPDATE SET member = excluded.member, expires_at = excluded.expires_at
+			WHERE consumer_leases.member = excluded.member OR consumer_leases.expires_at < ?`,
+			s.Group, topic, partition, member, now.Add(s.Broker.LeaseTTL).UnixNano(), now.UnixNano(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit records that msg was handled, unless member lost the lease on its
+// partition meanwhile: the message is then handled again by the member that
+// took it over.
+func (s *SQLiteSubscriber) commit(ctx context.Context, member string, msg *Message) error {
+	_, err := s.Broker.DB.ExecContext(ctx, `INSERT INTO consumer_offsets (group_id, topic, partition_id, next_offset)
+		SELECT group_id, topic, partition_id, ? FROM consumer_leases
+		WHERE group_id = ? AND topic = ? AND partition_id = ? AND member = ?
+		ON CONFLICT (group_id, topic, partition_id) DO UPDATE SET next_offset = excluded.next_offset`,
+		msg.Offset+1, s.Group, msg.Topic, msg.Partition, member,
+	)
+	return err
+}
+
+// release gives up the member's leases so other members take its partitions
+// over without waiting for them to expire.
+func (s *SQLiteSubscriber) release(ctx context.Context, member string) {
+	_, err := s.Broker.DB.ExecContext(ctx, "DELETE FROM consumer_leases WHERE group_id = ? AND member = ?", s.Group, member)
+	if err != nil {
+		log.Printf("Failed to release the partitions of %s: %v", s.Group, err)
+	}
+}
+
+// topic returns the quoted table of a topic, creating it on first use. The
+// log is stored in the order of its (partition_id, log_offset) key, which
+// serves the lookups of poll, Publish and Replay; topics have a table each.
+func (b *SQLiteBroker) topic(ctx context.Context, name string) (string, error) {
+	table := `"topic_` + strings.ReplaceAll(name, `"`, `""`) + `"`
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[name] {
+		return table, nil
+	}
+	_, err := b.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		partition_id INTEGER NOT NULL,
+		log_offset INTEGER NOT NULL,
+		message_key BLOB,
+		value BLOB,
+		headers TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (partition_id, log_offset)
+	) WITHOUT ROWID`)
+	if err != nil {
+		return "", err
+	}
+	b.topics[name] = true
+	return table, nil
+}
+
+func (b *SQLiteBroker) partition(key []byte) int32 {
+	if len(key) == 0 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.next++
+		return int32(b.next % uint32(b.Partitions))
+	}
+	return keyPartition(key, b.Partitions)
+}
+
+// sleep waits for d or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+type sqliteScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteMessage(row sqliteScanner, topic string) (*Message, error) {
+	msg := &Message{Topic: topic}
+	var headers string
+	var createdAt int64
+	if err := row.Scan(&msg.Partition, &msg.Offset, &msg.Key, &msg.Value, &headers, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(headers), &msg.Headers); err != nil {
+		return nil, err
+	}
+	msg.Timestamp = time.Unix(0, createdAt)
+	return msg, nil
+}
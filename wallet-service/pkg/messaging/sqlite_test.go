@@ -0,0 +1,257 @@
+package messaging
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newTestSQLiteBroker opens a broker on the event log at path, as a separate
+// process sharing the file would.
+func newTestSQLiteBroker(t *testing.T, path string, partitions int) *SQLiteBroker {
+	db, err := sql.Open("sqlite", SQLiteDSN(path))
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	broker, err := NewSQLiteBroker(db, partitions)
+	require.Nil(t, err)
+	broker.PollInterval = time.Millisecond
+	broker.RedeliveryDelay = time.Millisecond
+	return broker
+}
+
+func publishSQLiteTestMessages(t *testing.T, broker *SQLiteBroker, topic string, key string, values ...string) {
+	for _, value := range values {
+		msg := &Message{Topic: topic, Key: []byte(key), Value: []byte(value), Headers: map[string]string{"trace": value}}
+		require.Nil(t, broker.Publish(context.Background(), msg))
+	}
+}
+
+func TestSQLiteBrokerPublish(t *testing.T) {
+	broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 4)
+	publishSQLiteTestMessages(t, broker, "balances", "account1", "a", "b", "c")
+
+	messages, err := broker.Messages(context.Background(), "balances")
+
+	require.Nil(t, err)
+	require.Len(t, messages, 3)
+	for i, msg := range messages {
+		assert.Equal(t, "balances", msg.Topic)
+		assert.Equal(t, messages[0].Partition, msg.Partition)
+		assert.Equal(t, int64(i), msg.Offset)
+		assert.Equal(t, []byte("account1"), msg.Key)
+		assert.Equal(t, string(msg.Value), msg.Headers["trace"])
+		assert.False(t, msg.Timestamp.IsZero())
+	}
+}
+
+func TestSQLiteSubscriberSubscribe(t *testing.T) {
+	t.Run("consumes messages published by another process and resumes from the committed offset", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		wallet := newTestSQLiteBroker(t, path, 2)
+		balance := newTestSQLiteBroker(t, path, 2)
+		publishSQLiteTestMessages(t, wallet, "balances", "account1", "a", "b")
+
+		assert.Equal(t, []string{"a", "b"}, consumeN(t, balance.Subscriber("balance-service", "balances"), 2))
+
+		publishSQLiteTestMessages(t, wallet, "balances", "account1", "c")
+		assert.Equal(t, []string{"c"}, consumeN(t, balance.Subscriber("balance-service", "balances"), 1))
+	})
+
+	t.Run("replays the log for a new group and after a seek", func(t *testing.T) {
+		broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 1)
+		publishSQLiteTestMessages(t, broker, "balances", "account1", "a", "b")
+		consumeN(t, broker.Subscriber("balance-service", "balances"), 2)
+
+		assert.Equal(t, []string{"a", "b"}, consumeN(t, broker.Subscriber("audit", "balances"), 2))
+
+		require.Nil(t, broker.Seek(context.Background(), "balance-service", "balances", 0, 1))
+		assert.Equal(t, []string{"b"}, consumeN(t, broker.Subscriber("balance-service", "balances"), 1))
+	})
+
+	t.Run("redelivers a message whose handler failed", func(t *testing.T) {
+		broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 1)
+		publishSQLiteTestMessages(t, broker, "balances", "account1", "a", "b")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var got []string
+		err := broker.Subscriber("balance-service", "balances").Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+			got = append(got, string(msg.Value))
+			if len(got) == 1 {
+				return errors.New("database unavailable")
+			}
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "a", "b"}, got)
+		committed, err := broker.Committed(context.Background(), "balance-service", "balances", 0)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), committed)
+	})
+
+	t.Run("reads every topic of the subscription", func(t *testing.T) {
+		broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 1)
+		publishSQLiteTestMessages(t, broker, "transactions", "account1", "a")
+		publishSQLiteTestMessages(t, broker, "balances", "account1", "b")
+
+		got := consumeN(t, broker.Subscriber("balance-service", "balances", "transactions"), 2)
+
+		assert.ElementsMatch(t, []string{"a", "b"}, got)
+	})
+
+	t.Run("keeps handling other topics while one keeps failing", func(t *testing.T) {
+		broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 1)
+		publishSQLiteTestMessages(t, broker, "balances", "account1", "a")
+		publishSQLiteTestMessages(t, broker, "transactions", "account1", "b", "c")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var got []string
+		err := broker.Subscriber("balance-service", "balances", "transactions").Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+			if msg.Topic == "balances" {
+				return errors.New("database unavailable")
+			}
+			got = append(got, string(msg.Value))
+			if len(got) == 2 {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"b", "c"}, got)
+		committed, err := broker.Committed(context.Background(), "balance-service", "balances", 0)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), committed)
+	})
+
+	t.Run("hands every partition to one member of a group at a time", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		first := newTestSQLiteBroker(t, path, 2)
+		second := newTestSQLiteBroker(t, path, 2)
+		publishSQLiteTestMessages(t, first, "balances", "", "a", "b", "c", "d")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var mu sync.Mutex
+		handled := map[string]int{}
+		var wg sync.WaitGroup
+		for _, broker := range []*SQLiteBroker{first, second} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				broker.Subscriber("balance-service", "balances").Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+					mu.Lock()
+					defer mu.Unlock()
+					handled[string(msg.Value)]++
+					if len(handled) == 4 {
+						// Give a second member time to handle a message twice
+						time.AfterFunc(50*time.Millisecond, cancel)
+					}
+					return nil
+				})
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1, "d": 1}, handled)
+	})
+
+	t.Run("takes over the partitions of a member that stopped", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		first := newTestSQLiteBroker(t, path, 1)
+		second := newTestSQLiteBroker(t, path, 1)
+		publishSQLiteTestMessages(t, first, "balances", "account1", "a", "b")
+
+		assert.Equal(t, []string{"a"}, consumeN(t, first.Subscriber("balance-service", "balances"), 1))
+		assert.Equal(t, []string{"b"}, consumeN(t, second.Subscriber("balance-service", "balances"), 1))
+	})
+
+	t.Run("takes over the partitions of a member whose lease expired", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		first := newTestSQLiteBroker(t, path, 1)
+		second := newTestSQLiteBroker(t, path, 1)
+		second.LeaseTTL = time.Millisecond
+		publishSQLiteTestMessages(t, first, "balances", "account1", "a", "b")
+		// A member that stalled before giving up its lease
+		_, err := first.DB.Exec("INSERT INTO consumer_leases VALUES ('balance-service', 'balances', 0, 'stalled', ?)", time.Now().UnixNano())
+		require.Nil(t, err)
+
+		assert.Equal(t, []string{"a", "b"}, consumeN(t, second.Subscriber("balance-service", "balances"), 2))
+	})
+}
+
+func TestSQLiteBrokerReplay(t *testing.T) {
+	ctx := context.Background()
+	broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 1)
+	publishSQLiteTestMessages(t, broker, "balances", "account1", "a", "b")
+	require.Nil(t, broker.Publish(ctx, &Message{Topic: "balances", Key: []byte("account1"), Value: []byte("c"), Timestamp: time.Now().Add(time.Hour)}))
+
+	replay := func(from ReplayPosition) ([]string, Offsets) {
+		var got []string
+		ends, err := broker.Replay(ctx, []string{"balances"}, from, func(ctx context.Context, msg *Message) error {
+			got = append(got, string(msg.Value))
+			return nil
+		})
+		require.Nil(t, err)
+		return got, ends
+	}
+
+	got, ends := replay(ReplayPosition{})
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+	assert.Equal(t, Offsets{{Topic: "balances", Partition: 0}: 3}, ends)
+
+	got, _ = replay(ReplayPosition{Offset: 1})
+	assert.Equal(t, []string{"b", "c"}, got)
+	got, _ = replay(ReplayPosition{Timestamp: time.Now().Add(time.Minute)})
+	assert.Equal(t, []string{"c"}, got)
+
+	// A later replay picks up where an earlier one stopped
+	publishSQLiteTestMessages(t, broker, "balances", "account1", "d")
+	got, _ = replay(ReplayPosition{Offsets: ends})
+	assert.Equal(t, []string{"d"}, got)
+
+	// Replaying does not commit anything for live groups
+	offset, err := broker.Committed(ctx, "balance-service", "balances", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+}
+
+func TestSQLiteBrokerLookupsUseTheLogKey(t *testing.T) {
+	broker := newTestSQLiteBroker(t, filepath.Join(t.TempDir(), "events.db"), 2)
+	publishSQLiteTestMessages(t, broker, "balances", "account1", "a")
+
+	plan := func(query string, args ...interface{}) string {
+		rows, err := broker.DB.Query("EXPLAIN QUERY PLAN "+query, args...)
+		require.Nil(t, err)
+		defer rows.Close()
+		details := ""
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			require.Nil(t, rows.Scan(&id, &parent, &unused, &detail))
+			details += detail + "\n"
+		}
+		return details
+	}
+
+	// The next offset of a partition on Publish
+	assert.Contains(t, plan(`SELECT COALESCE(MAX(log_offset) + 1, 0) FROM "topic_balances" WHERE partition_id = ?`, 0),
+		"SEARCH topic_balances USING PRIMARY KEY (partition_id=?)")
+	// The next message of a partition on poll
+	assert.Contains(t, plan(`SELECT value FROM "topic_balances" WHERE partition_id = ? AND log_offset >= ? ORDER BY log_offset LIMIT 1`, 0, 0),
+		"SEARCH topic_balances USING PRIMARY KEY (partition_id=? AND log_offset>?)")
+}
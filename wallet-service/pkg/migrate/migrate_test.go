@@ -0,0 +1,152 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+	"wallet/pkg/dialect"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_items.up.sql": {Data: []byte(`-- Items
+CREATE TABLE items (
+    id VARCHAR(255) PRIMARY KEY,
+    price DECIMAL(15,2) NOT NULL
+);
+
+CREATE INDEX idx_items_price ON items (price);
+`)},
+	"0001_create_items.down.sql":  {Data: []byte("DROP TABLE items;\n")},
+	"0002_add_item_name.up.sql":   {Data: []byte("ALTER TABLE items ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '';\n")},
+	"0002_add_item_name.down.sql": {Data: []byte("ALTER TABLE items DROP COLUMN name;\n")},
+	"README.md":                   {Data: []byte("not a migration")},
+}
+
+func newTestMigrator(t *testing.T) *Migrator {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewMigrator(db, dialect.SQLite, "schema_migrations", testMigrations)
+	require.Nil(t, err)
+	return migrator
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations)
+	require.Nil(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_items", migrations[0].Name)
+	assert.Equal(t, "add_item_name", migrations[1].Name)
+
+	_, err = Load(fstest.MapFS{"0001_create_items.up.sql": {Data: []byte("CREATE TABLE items (id TEXT);")}})
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+
+	_, err = Load(fstest.MapFS{"create_items.sql": {Data: []byte("CREATE TABLE items (id TEXT);")}})
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+}
+
+func TestStatements(t *testing.T) {
+	statements := Statements(`-- Items
+CREATE TABLE items (
+    id TEXT
+);
+
+INSERT INTO items (id) VALUES ('a');
+DELETE FROM items`)
+
+	assert.Equal(t, []string{
+		"CREATE TABLE items (\n    id TEXT\n)",
+		"INSERT INTO items (id) VALUES ('a')",
+		"DELETE FROM items",
+	}, statements)
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+
+	applied, err := migrator.Up(ctx)
+	require.Nil(t, err)
+	assert.Len(t, applied, 2)
+	_, err = migrator.DB.Exec(`INSERT INTO items (id, price, name) VALUES ('a', 9.99, 'pen')`)
+	assert.Nil(t, err)
+
+	version, err := migrator.Version(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), version)
+
+	// Applied migrations are not run again
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(ctx, 1)
+	require.Nil(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, int64(2), reverted[0].Version)
+	_, err = migrator.DB.Exec(`INSERT INTO items (id, price, name) VALUES ('b', 1, 'cup')`)
+	assert.NotNil(t, err)
+
+	status, err := migrator.Status(ctx)
+	require.Nil(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[0].AppliedAt.IsZero())
+	assert.False(t, status[1].Applied)
+
+	reverted, err = migrator.Down(ctx, 5)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, 1)
+	version, err = migrator.Version(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), version)
+}
+
+func TestMigratorUpStopsAtFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+	migrator.Migrations[1].Up = "ALTER TABLE missing ADD COLUMN name TEXT;"
+
+	applied, err := migrator.Up(ctx)
+	assert.ErrorContains(t, err, "migration 2_add_item_name")
+	assert.Len(t, applied, 1)
+
+	version, err := migrator.Version(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestMigratorForce(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+	// A schema created before migrations were tracked
+	_, err := migrator.DB.Exec(`CREATE TABLE items (id VARCHAR(255) PRIMARY KEY, price DECIMAL(15,2) NOT NULL)`)
+	require.Nil(t, err)
+
+	require.Nil(t, migrator.Force(ctx, 1))
+	applied, err := migrator.Up(ctx)
+	require.Nil(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, int64(2), applied[0].Version)
+
+	assert.ErrorIs(t, migrator.Force(ctx, 3), ErrInvalidMigration)
+}
+
+func TestMigratorRejectsUnknownVersions(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+	_, err := migrator.Up(ctx)
+	require.Nil(t, err)
+
+	// An older binary against a database migrated by a newer one
+	migrator.Migrations = migrator.Migrations[:1]
+	_, err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
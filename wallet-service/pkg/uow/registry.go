@@ -0,0 +1,50 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var ErrRepositoryType = errors.New("repository has the wrong type")
+
+// RepositoryName is the name a repository of type T is registered under by
+// Register, e.g. "wallet/internal/gateway.AccountGateway".
+func RepositoryName[T any]() string {
+	return typeName(reflect.TypeFor[T]())
+}
+
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return "*" + typeName(t.Elem())
+	}
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// Register adds a factory for repositories of type T, which is usually the
+// gateway interface use cases depend on.
+func Register[T any](u UowInterface, factory func(tx *sql.Tx) T) {
+	u.Register(RepositoryName[T](), func(tx *sql.Tx) interface{} {
+		return factory(tx)
+	})
+}
+
+// Get returns the repository of type T bound to the transaction of ctx.
+func Get[T any](ctx context.Context, u UowInterface) (T, error) {
+	var zero T
+	name := RepositoryName[T]()
+	repository, err := u.GetRepository(ctx, name)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := repository.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s is %T", ErrRepositoryType, name, repository)
+	}
+	return typed, nil
+}
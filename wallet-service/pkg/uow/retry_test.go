@@ -0,0 +1,151 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}))
+	assert.True(t, IsRetryable(fmt.Errorf("failed to update account balance: %w", &mysql.MySQLError{Number: 1205})))
+	assert.False(t, IsRetryable(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+	assert.True(t, IsRetryable(&pgconn.PgError{Code: "40P01", Message: "deadlock detected"}))
+	assert.True(t, IsRetryable(&pgconn.PgError{Code: "40001"}))
+	assert.False(t, IsRetryable(&pgconn.PgError{Code: "23505", Message: "duplicate key value"}))
+	assert.False(t, IsRetryable(sql.ErrNoRows))
+	assert.False(t, IsRetryable(errors.New("boom")))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+
+	for i := 0; i < 20; i++ {
+		first := policy.Backoff(1)
+		assert.GreaterOrEqual(t, first, 50*time.Millisecond)
+		assert.LessOrEqual(t, first, 100*time.Millisecond)
+
+		capped := policy.Backoff(5)
+		assert.GreaterOrEqual(t, capped, 150*time.Millisecond)
+		assert.LessOrEqual(t, capped, 300*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyRun(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213}
+
+	t.Run("retries retryable errors until fn succeeds", func(t *testing.T) {
+		retries := []int{}
+		policy := NewRetryPolicy()
+		policy.Sleep = func(context.Context, time.Duration) error { return nil }
+		policy.OnRetry = func(attempt int, err error) {
+			retries = append(retries, attempt)
+		}
+
+		attempts := []int{}
+		err := policy.Run(context.Background(), func(ctx context.Context) error {
+			attempts = append(attempts, Attempt(ctx))
+			if len(attempts) < 3 {
+				return deadlock
+			}
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []int{1, 2, 3}, attempts)
+		assert.Equal(t, []int{2, 3}, retries)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		policy := NewRetryPolicy()
+		calls := 0
+
+		err := policy.Run(context.Background(), func(ctx context.Context) error {
+			calls++
+			return errors.New("insufficient funds")
+		})
+
+		assert.EqualError(t, err, "insufficient funds")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		policy := NewRetryPolicy()
+		policy.MaxAttempts = 3
+		policy.Sleep = func(context.Context, time.Duration) error { return nil }
+
+		err := policy.Run(context.Background(), func(ctx context.Context) error {
+			return deadlock
+		})
+
+		var retryErr *RetryError
+		require.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 3, retryErr.Attempts)
+		assert.ErrorIs(t, err, deadlock)
+	})
+
+	t.Run("stops waiting when ctx is done", func(t *testing.T) {
+		policy := NewRetryPolicy()
+		policy.InitialBackoff = time.Hour
+		policy.MaxBackoff = time.Hour
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := policy.Run(ctx, func(ctx context.Context) error {
+			return deadlock
+		})
+
+		var retryErr *RetryError
+		require.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 1, retryErr.Attempts)
+		assert.ErrorIs(t, err, deadlock)
+	})
+}
+
+func TestUowDoRetriesBusyDatabase(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "uow.db")
+	// Without a busy timeout SQLite fails at once while another connection
+	// holds the write lock
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)")
+	require.Nil(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE items (id TEXT PRIMARY KEY)`)
+	require.Nil(t, err)
+
+	locker, err := db.Conn(ctx)
+	require.Nil(t, err)
+	defer locker.Close()
+	_, err = locker.ExecContext(ctx, "BEGIN IMMEDIATE")
+	require.Nil(t, err)
+
+	u := NewUow(ctx, db)
+	u.Register("ItemRepository", func(tx *sql.Tx) interface{} {
+		return &testRepository{tx: tx}
+	})
+	u.RetryPolicy.Sleep = func(context.Context, time.Duration) error { return nil }
+	u.RetryPolicy.OnRetry = func(attempt int, err error) {
+		assert.True(t, IsRetryable(err))
+		_, err = locker.ExecContext(ctx, "ROLLBACK")
+		assert.Nil(t, err)
+	}
+
+	attempts := 0
+	err = u.Do(ctx, func(ctx context.Context) error {
+		attempts = Attempt(ctx)
+		return addItem(ctx, u, "a")
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 1, countItems(t, u))
+}